@@ -0,0 +1,67 @@
+package parser
+
+import (
+	"sethwklein.net/thefile/tokenizer"
+)
+
+// Alphabet gives the tokens a tokenizer produces for each kind of line: clear,
+// title, alternate title, other, and end of file.
+type Alphabet struct {
+	C, T, A, O, E tokenizer.Token
+}
+
+// MachineAlphabet is what ParseTokens and the machine read. It has no
+// alternate titles, since those are read as titles.
+var MachineAlphabet = Alphabet{C: 'c', T: 't', A: 't', O: 'o', E: 'e'}
+
+// DefaultAlphabet is what tokenizer.Default produces.
+var DefaultAlphabet = NewAlphabet(tokenizer.Default)
+
+// NewAlphabet returns the alphabet t produces.
+func NewAlphabet(t tokenizer.Tokenizer) Alphabet {
+	return Alphabet{C: t.C, T: t.T, A: t.A, O: t.O, E: t.E}
+}
+
+// Token returns the token the machine reads for token, which is in alphabet.
+// Tokens not in alphabet are returned as 0, which the machine rejects.
+func (alphabet Alphabet) Token(token tokenizer.Token) tokenizer.Token {
+	switch token {
+	case alphabet.C:
+		return 'c'
+	case alphabet.T, alphabet.A:
+		return 't'
+	case alphabet.O:
+		return 'o'
+	case alphabet.E:
+		return 'e'
+	}
+	return 0
+}
+
+// Map returns the tokens the machine reads for tokens, which are in alphabet.
+// If alphabet is MachineAlphabet, it returns tokens.
+func (alphabet Alphabet) Map(tokens []tokenizer.Token) []tokenizer.Token {
+	if alphabet == MachineAlphabet {
+		return tokens
+	}
+	mapped := make([]tokenizer.Token, len(tokens))
+	for i, token := range tokens {
+		mapped[i] = alphabet.Token(token)
+	}
+	return mapped
+}
+
+// ParseWith is like ParseTokens, but reads the tokens t produces, reading
+// alternate titles as titles. Tokens in errors are as given.
+func ParseWith(t tokenizer.Tokenizer, tokens []tokenizer.Token) ([]Page, error) {
+	alphabet := NewAlphabet(t)
+	if len(tokens) < 1 || tokens[len(tokens)-1] != alphabet.E {
+		return nil, ErrMissingEOF
+	}
+	pages, err := ParseTokens(alphabet.Map(tokens))
+	if pe, ok := err.(ParseError); ok {
+		pe.Token = tokens[pe.Index]
+		err = pe
+	}
+	return pages, err
+}
@@ -0,0 +1,39 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"sethwklein.net/thefile/tokenizer"
+)
+
+func TestParseWith(t *testing.T) {
+	upper := tokenizer.Tokenizer{C: 'C', T: 'T', A: 'A', O: 'O', E: 'E'}
+	for _, test := range tests {
+		input := strings.Replace(test.input, " ", "", -1)
+		expected, _ := ParseTokens(toTokens(input))
+
+		actual, err := ParseWith(upper, toTokens(strings.ToUpper(input)))
+		if err != nil || !reflect.DeepEqual(expected, actual) {
+			t.Errorf("\ninput:    %v\nexpected: %v\nactual:   %v, %v",
+				test.input, expected, actual, err)
+		}
+
+		// alternate titles are titles
+		alternates := strings.Replace(input, "t", "a", -1)
+		actual, err = ParseWith(tokenizer.Default, toTokens(alternates))
+		if err != nil || !reflect.DeepEqual(expected, actual) {
+			t.Errorf("\ninput:    %v\nexpected: %v\nactual:   %v, %v",
+				alternates, expected, actual, err)
+		}
+	}
+
+	_, err := ParseWith(upper, toTokens("TCcE"))
+	if expected := (ParseError{2, 'c', "Hc"}); err != expected {
+		t.Errorf("\nexpected: %v\nactual:   %v", expected, err)
+	}
+	if _, err := ParseWith(upper, toTokens("Te")); err != ErrMissingEOF {
+		t.Errorf("expected ErrMissingEOF, got: %v", err)
+	}
+}
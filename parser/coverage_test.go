@@ -0,0 +1,46 @@
+package parser
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"sethwklein.net/thefile/parser/spec"
+)
+
+// checkCoverage fails t if any transition in machine.txt is not taken by
+// coverParseTokens when run on inputs, which are token strings as in tests.
+func checkCoverage(t *testing.T, inputs []string) {
+	txt, err := ioutil.ReadFile("machine.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := spec.Parse("machine.txt", txt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key := range coverage {
+		delete(coverage, key)
+	}
+	for _, input := range inputs {
+		coverParseTokens(toTokens(strings.Replace(input, " ", "", -1)))
+	}
+
+	for _, transition := range m.Transitions() {
+		key := transition.String()
+		t.Logf("%5d %s", coverage[key], key)
+		if coverage[key] < 1 {
+			t.Errorf("machine.txt:%d: %s not taken by tests",
+				transition.Line, key)
+		}
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	inputs := make([]string, len(tests))
+	for i, test := range tests {
+		inputs[i] = test.input
+	}
+	checkCoverage(t, inputs)
+}
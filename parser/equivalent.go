@@ -0,0 +1,58 @@
+package parser
+
+import (
+	"reflect"
+
+	"sethwklein.net/thefile/tokenizer"
+)
+
+// Distinguish returns the shortest token string for which a and b find
+// different pages or return different errors, or nil if there is none. It
+// tries every string of clears, titles, and others up to maxLength long,
+// followed by the end of file token, shortest first, so nil means only that
+// a and b agree on every file up to maxLength lines.
+func Distinguish(a, b *Machine, maxLength int) []tokenizer.Token {
+	var found []tokenizer.Token
+	enumerate("cto", maxLength, func(tokens []tokenizer.Token) bool {
+		aPages, aErr := a.Parse(tokens)
+		bPages, bErr := b.Parse(tokens)
+		if !reflect.DeepEqual(aPages, bPages) || aErr != bErr {
+			found = append([]tokenizer.Token(nil), tokens...)
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+// enumerate calls try with every string of the tokens in alphabet up to
+// maxLength long, shortest first, each followed by the end of file token,
+// until try returns false. Try must not keep tokens, which is reused.
+func enumerate(alphabet string, maxLength int, try func(tokens []tokenizer.Token) bool) {
+	for length := 0; length <= maxLength; length++ {
+		digits := make([]int, length)
+		tokens := make([]tokenizer.Token, length+1)
+		tokens[length] = 'e'
+		for {
+			for i, d := range digits {
+				tokens[i] = tokenizer.Token(alphabet[d])
+			}
+			if !try(tokens) {
+				return
+			}
+
+			// next string of this length
+			i := length - 1
+			for ; i >= 0; i-- {
+				digits[i]++
+				if digits[i] < len(alphabet) {
+					break
+				}
+				digits[i] = 0
+			}
+			if i < 0 {
+				break
+			}
+		}
+	}
+}
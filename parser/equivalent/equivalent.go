@@ -0,0 +1,94 @@
+// Command equivalent checks that two machine descriptions, such as
+// machine.txt before and after a refactor, find the same pages. It tries every
+// file up to -maxlen lines long and prints the shortest one for which they
+// differ, along with what each found.
+//
+// Usage:
+//
+//	equivalent [-maxlen n] old.txt new.txt
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"sethwklein.net/thefile/parser"
+)
+
+// DifferentError is returned when the descriptions find different pages.
+type DifferentError struct {
+	Old, New string
+}
+
+func (err DifferentError) Error() string {
+	return fmt.Sprintf("%s and %s are different", err.Old, err.New)
+}
+
+// load reads the machine description in the named file, which may be
+// anywhere, not only below the current directory.
+func load(name string) (*parser.Machine, error) {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return nil, err
+	}
+	return parser.LoadMachine(os.DirFS(filepath.Dir(abs)), filepath.Base(abs))
+}
+
+func mainError() error {
+	maxLength := flag.Int("maxlen", 12,
+		"longest token string, not counting e, to try")
+	flag.Parse()
+	if flag.NArg() != 2 {
+		return fmt.Errorf("expected two machine descriptions, got %d",
+			flag.NArg())
+	}
+	oldName, newName := flag.Arg(0), flag.Arg(1)
+
+	old, err := load(oldName)
+	if err != nil {
+		return err
+	}
+	changed, err := load(newName)
+	if err != nil {
+		return err
+	}
+
+	tokens := parser.Distinguish(old, changed, *maxLength)
+	if tokens == nil {
+		fmt.Printf("equivalent for all token strings up to %d long\n",
+			*maxLength)
+		return nil
+	}
+	input := make([]byte, len(tokens))
+	for i, token := range tokens {
+		input[i] = byte(token)
+	}
+	fmt.Printf("input: %s\n", input)
+	for _, m := range []struct {
+		name    string
+		machine *parser.Machine
+	}{{oldName, old}, {newName, changed}} {
+		pages, err := m.machine.Parse(tokens)
+		fmt.Printf("%s: %v", m.name, pages)
+		if err != nil {
+			fmt.Printf(" (%v)", err)
+		}
+		fmt.Println()
+	}
+	return DifferentError{oldName, newName}
+}
+
+func mainCode() int {
+	err := mainError()
+	if err == nil {
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "%v: Error: %v\n", filepath.Base(os.Args[0]), err)
+	return 1
+}
+
+func main() {
+	os.Exit(mainCode())
+}
@@ -0,0 +1,43 @@
+package parser
+
+import (
+	"os"
+	"regexp"
+	"testing"
+)
+
+func TestDistinguish(t *testing.T) {
+	txt, err := os.ReadFile("machine.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewMachine("machine.txt", string(txt))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// renaming a state changes nothing
+	name := regexp.MustCompile(`\b` + m.Spec().States[1].Name + `\b`)
+	renamed, err := NewMachine("renamed",
+		name.ReplaceAllString(string(txt), "Renamed"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokens := Distinguish(m, renamed, 8); tokens != nil {
+		t.Errorf("renaming changed the pages for %v", tokensToString(tokens))
+	}
+
+	// every line is its own page
+	each, err := NewMachine("each", `
+I+c: I (new page, head.empty(i), body.low = i, body.high = i + 1)
+I+t: I (new page, head.low = i, head.high = i + 1, body.empty(head.high))
+I+o: I (new page, head.empty(i), body.low = i, body.high = i + 1)
+I+e: - (terminate)
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := tokensToString(Distinguish(m, each, 8)); actual != "{ce}" {
+		t.Errorf("\nexpected: {ce}\nactual:   %v", actual)
+	}
+}
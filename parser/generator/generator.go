@@ -1,46 +1,172 @@
-// Command generator creates machine.go from machine.txt
+// Command generator creates machine.go from machine.txt, along with
+// machine.dot and machine.md for reviewing changes to machine.txt and
+// machine_cover_test.go for checking that the tests take every transition.
+//
+// Flags change the files read and written and the names generated, so other
+// grammars can be generated into other files or packages. The package must
+// declare Page, Part, Step, ParseError, and ErrMissingEOF as package parser
+// does, and if a stream is generated, the stream type, its state type, and
+// its Done error, named like ErrStreamDone. With -check, nothing is written,
+// and the command fails if any output would be different, meaning that it is
+// stale.
 package main
 
 import (
-	"bufio"
 	"bytes"
+	"flag"
 	"fmt"
 	"go/format"
 	"io/ioutil"
 	"os"
 	"path/filepath"
-	"regexp"
+	"strings"
 
-	"sethwklein.net/go/errors"
+	"sethwklein.net/thefile/parser/spec"
 )
 
-func mainError() (err error) {
-	txt, err := ioutil.ReadFile("machine.txt")
-	if err != nil {
-		return err
+// target names the Go code for an action target or expression name. Note is
+// the name of the note variable.
+func target(name, note string) string {
+	switch name {
+	case "note":
+		return note
+	case "head.low", "body.low":
+		return strings.TrimSuffix(name, "low") + "Low"
+	case "head.high", "body.high":
+		return strings.TrimSuffix(name, "high") + "High"
 	}
-	scanner := bufio.NewScanner(bytes.NewReader(txt))
-	defer func() {
-		// can't see how there would be any
-		err = errors.Append(err, scanner.Err())
-	}()
+	return name
+}
 
-	buffer := &bytes.Buffer{}
+// actions returns the Go statements for the actions of t, one per line.
+// NewPage, note, and terminate are the Go code for those names.
+func actions(t *spec.Transition, newPage, note, terminate string) string {
+	var lines []string
+	for _, a := range t.Actions {
+		expr := a.Expr
+		expr.Name = target(expr.Name, note)
+		switch a.Verb {
+		case spec.NewPage:
+			lines = append(lines, newPage)
+		case spec.Set:
+			lines = append(lines, target(a.Target, note)+" = "+expr.String())
+		case spec.Empty:
+			lines = append(lines, a.Target+".empty("+expr.String()+")")
+		case spec.Terminate:
+			lines = append(lines, terminate)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
 
-	buffer.WriteString(`package parser
+// names are the names of the generated things, from the flags.
+type names struct {
+	// pkg is the package name.
+	pkg string
+	// parse, trace, and cover are the names of the functions generated by
+	// writeParse, and coverage is the map cover counts transitions in.
+	parse, trace, cover, coverage string
+	// stream is the name of the type that gets the Push method, or "" for
+	// none. Its state type and the state constants start with stream with a
+	// lower case first letter.
+	stream string
+}
 
-		// AUTOMATICALLY GENERATED! DO NOT EDIT!
-		// recreate with: go run generator/generator.go
+// newNames returns the names to use when generating the parse function parse
+// and the stream type stream. For ParseTokens and Stream, they are the names
+// package parser uses.
+func newNames(pkg, parse, stream string) names {
+	n := names{
+		pkg:      pkg,
+		parse:    parse,
+		trace:    parse + "Trace",
+		cover:    "cover" + parse,
+		coverage: "coverage",
+		stream:   stream,
+	}
+	if strings.HasPrefix(parse, "Parse") {
+		n.trace = "Trace" + strings.TrimPrefix(parse, "Parse")
+	}
+	if parse != "ParseTokens" {
+		// keep the maps of multiple grammars in one package apart
+		n.coverage += parse
+	}
+	return n
+}
 
-		import (
-			"errors"
-			"sethwklein.net/thefile/tokenizer"
-		)
+// lower returns name with a lower case first letter.
+func lower(name string) string {
+	if name == "" {
+		return name
+	}
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+// variant describes a function generated by writeParse.
+type variant struct {
+	// head is the doc comment and the function up to its parameters.
+	head string
+	// results is the function's result list.
+	results string
+	// returns is inserted after "return" and the pages result.
+	returns string
+	// hit, if not nil, returns a statement run for each transition taken.
+	hit func(*spec.Transition) string
+}
+
+func (n names) plain() variant {
+	return variant{
+		head: fmt.Sprintf(`
+			// %s finds the pages in tokens. If tokens is not terminated
+			// by an end of file token, it returns ErrMissingEOF. If it finds an
+			// unknown token, it returns a ParseError.
+			func %[1]s(`, n.parse),
+		results: "(pages []Page, err error)",
+	}
+}
+
+func (n names) coverVariant() variant {
+	return variant{
+		head: fmt.Sprintf(`
+			// %s counts the transitions taken by %s,
+			// keyed like "Q+c".
+			var %[1]s = make(map[string]int)
 
-		// Parse finds the pages in tokens.
-		func Parse(tokens []tokenizer.Token) (pages []Page) {
-			if len(tokens) < 1 || tokens[len(tokens)-1] != tokenizer.Default.E {
-				panic(errors.New("missing end of file token"))
+			// %[2]s is %[3]s, but counts transitions in
+			// %[1]s.
+			func %[2]s(`, n.coverage, n.cover, n.parse),
+		results: "(pages []Page, err error)",
+		hit: func(t *spec.Transition) string {
+			return fmt.Sprintf("%s[%q]++", n.coverage, t)
+		},
+	}
+}
+
+func (n names) traceVariant() variant {
+	return variant{
+		head: fmt.Sprintf(`
+			// %s is %s, but also returns a Step for each
+			// token read, even if it returns an error.
+			func %[1]s(`, n.trace, n.parse),
+		results: "(pages []Page, steps []Step, err error)",
+		returns: ", steps",
+		hit: func(t *spec.Transition) string {
+			next := t.Next
+			if t.Final() {
+				next = "-"
+			}
+			return fmt.Sprintf("steps = append(steps, Step{i, tokens[i], %q, %q, %q})",
+				t.From, next, t.ActionText())
+		},
+	}
+}
+
+// writeParse writes the function described by v.
+func writeParse(buffer *bytes.Buffer, m *spec.Spec, v variant) {
+	buffer.WriteString(v.head)
+	fmt.Fprintf(buffer, `tokens []tokenizer.Token) %s {
+			if len(tokens) < 1 || tokens[len(tokens)-1] != 'e' {
+				return nil%s, ErrMissingEOF
 			}
 
 			var head, body *Part
@@ -51,85 +177,231 @@ func mainError() (err error) {
 			}
 			var note int
 
-	`)
+	`, v.results, v.returns)
 
-	first := true
-	open := false
-	openSwitch := func(label []byte) {
-		if first {
+	for n, state := range m.States {
+		if n == 0 {
 			buffer.WriteString("// ")
 		}
-		buffer.Write(label)
-		buffer.WriteString(":\n")
-		if first {
+		fmt.Fprintf(buffer, "%s:\n", state.Name)
+		if n == 0 {
 			buffer.WriteString("i := 0\n")
-			first = false
 		} else {
 			buffer.WriteString("i++\n")
 		}
 		buffer.WriteString("switch tokens[i] {\n")
-		open = true
-	}
-	closeSwitch := func() {
-		buffer.WriteString(`default:
-				panic(TokenError{tokens[i], i})
+		for _, t := range state.Transitions {
+			fmt.Fprintf(buffer, "case '%c':\n", t.Token)
+			if v.hit != nil {
+				buffer.WriteString(v.hit(t))
+				buffer.WriteByte('\n')
+			}
+			if len(t.Actions) > 0 {
+				buffer.WriteString(actions(t, "newPage()", "note",
+					"return pages"+v.returns+", nil"))
+				buffer.WriteByte('\n')
+			}
+			if !t.Final() {
+				fmt.Fprintf(buffer, "goto %s\n", t.Next)
+			}
+		}
+		fmt.Fprintf(buffer, `default:
+				return nil%s, ParseError{i, tokens[i], %q}
 			}
-		`)
-		open = false
+		`, v.returns, state.Name)
 	}
 
-	rCase := regexp.MustCompile(`^([A-Za-z]+)\+([a-z]): ([A-Za-z-]+) +\((.*)\)$`)
-	for scanner.Scan() {
-		matches := rCase.FindSubmatch(scanner.Bytes())
-		if len(matches) < 1 {
-			continue
-		}
-		if !open {
-			openSwitch(matches[1])
-		}
+	buffer.WriteString("}\n")
+}
 
-		buffer.WriteString("case '")
-		buffer.Write(matches[2])
-		buffer.WriteString("':\n")
-
-		if actions := matches[4]; len(actions) > 0 {
-			actions = bytes.Replace(actions, []byte{','},
-				[]byte{';'}, -1)
-			actions = bytes.Replace(actions, []byte("new page"),
-				[]byte("newPage()"), -1)
-			actions = bytes.Replace(actions, []byte(".low"),
-				[]byte(".Low"), -1)
-			actions = bytes.Replace(actions, []byte(".high"),
-				[]byte(".High"), -1)
-			actions = bytes.Replace(actions, []byte("terminate"),
-				[]byte("return pages"), -1)
-			buffer.Write(actions)
-			buffer.WriteByte('\n')
+// writeStream writes the Push method for the stream type named stream. Its
+// constructor is named New followed by stream, and the error returned after
+// the end of file token is named Err, stream, Done, as in NewStream and
+// ErrStreamDone.
+func writeStream(buffer *bytes.Buffer, m *spec.Spec, stream string) {
+	prefix := lower(stream)
+	buffer.WriteString("\nconst (\n")
+	for i, state := range m.States {
+		fmt.Fprintf(buffer, "%s%s", prefix, state.Name)
+		if i == 0 {
+			fmt.Fprintf(buffer, " %sState = iota", prefix)
 		}
+		buffer.WriteByte('\n')
+	}
+	fmt.Fprintf(buffer, `%sDone
+		)
 
-		if len(matches[3]) > 0 && matches[3][0] != '-' {
-			buffer.WriteString("goto ")
-			buffer.Write(matches[3])
-			buffer.WriteByte('\n')
-		} else {
-			closeSwitch()
+		// Push feeds the next token to s. Completed pages are passed to the
+		// callback given to New%[2]s. If token is invalid, Push returns a
+		// ParseError and s is left unchanged. After the end of file token,
+		// Push returns Err%[2]sDone.
+		func (s *%[2]s) Push(token tokenizer.Token) error {
+			i := s.i
+			head, body := &s.page.Head, &s.page.Body
+			switch s.state {
+	`, prefix, stream)
+
+	for _, state := range m.States {
+		fmt.Fprintf(buffer, "case %s%s:\nswitch token {\n", prefix, state.Name)
+		for _, t := range state.Transitions {
+			fmt.Fprintf(buffer, "case '%c':\n", t.Token)
+			if len(t.Actions) > 0 {
+				buffer.WriteString(actions(t, "s.newPage()", "s.note",
+					"s.terminate()"))
+				buffer.WriteByte('\n')
+			}
+			if !t.Final() {
+				fmt.Fprintf(buffer, "s.state = %s%s\n", prefix, t.Next)
+			}
 		}
+		fmt.Fprintf(buffer, `default:
+				return ParseError{i, token, %q}
+			}
+		`, state.Name)
 	}
 
-	buffer.WriteString("}")
+	fmt.Fprintf(buffer, `default:
+				return Err%sDone
+			}
+			s.i++
+			return nil
+		}
+	`, stream)
+}
+
+// writeHeader writes the start of a Go file.
+func writeHeader(buffer *bytes.Buffer, n names) {
+	fmt.Fprintf(buffer, `package %s
+
+		// AUTOMATICALLY GENERATED! DO NOT EDIT!
+		// recreate with: go generate
+
+		import (
+			"sethwklein.net/thefile/tokenizer"
+		)
+	`, n.pkg)
+}
+
+func generateGo(m *spec.Spec, n names) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+
+	writeHeader(buffer, n)
+	writeParse(buffer, m, n.plain())
+	writeParse(buffer, m, n.traceVariant())
+	if n.stream != "" {
+		writeStream(buffer, m, n.stream)
+	}
 
 	// used for debugging
 	// os.Stdout.Write(buffer.Bytes())
 
-	formatted, err := format.Source(buffer.Bytes())
+	return format.Source(buffer.Bytes())
+}
+
+func generateCover(m *spec.Spec, n names) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+
+	writeHeader(buffer, n)
+	writeParse(buffer, m, n.coverVariant())
+
+	return format.Source(buffer.Bytes())
+}
+
+func generateDot(m *spec.Spec, n names) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	err := m.WriteDot(buffer)
+	return buffer.Bytes(), err
+}
+
+func generateMarkdown(m *spec.Spec, n names) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	err := m.WriteMarkdown(buffer)
+	return buffer.Bytes(), err
+}
+
+// modes maps -mode values to the suffixes that replace .go in the -out file
+// name to name their output files, and the functions that create them.
+var modes = []struct {
+	name, suffix string
+	generate     func(*spec.Spec, names) ([]byte, error)
+}{
+	{"go", ".go", generateGo},
+	{"dot", ".dot", generateDot},
+	{"markdown", ".md", generateMarkdown},
+	{"cover", "_cover_test.go", generateCover},
+}
+
+// StaleError is returned with -check when files are out of date.
+type StaleError struct {
+	Files []string
+}
+
+func (err StaleError) Error() string {
+	return "out of date, run go generate: " + strings.Join(err.Files, ", ")
+}
+
+func mainError() error {
+	mode := flag.String("mode", "all",
+		"what to generate: go, dot (Graphviz), markdown, cover (for tests), or all")
+	in := flag.String("in", "machine.txt", "machine description to read")
+	out := flag.String("out", "machine.go",
+		"Go file to write; the other files are named after it")
+	pkg := flag.String("package", "parser", "package of the Go files")
+	parse := flag.String("func", "ParseTokens",
+		"parse function name; the trace and cover functions are named after it")
+	stream := flag.String("stream", "Stream",
+		"type to generate the Push method for, or empty for none")
+	check := flag.Bool("check", false,
+		"write nothing, but fail if any file would be different")
+	flag.Parse()
+	if flag.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %v", flag.Args())
+	}
+	n := newNames(*pkg, *parse, *stream)
+
+	txt, err := ioutil.ReadFile(*in)
 	if err != nil {
 		return err
 	}
-
-	err = ioutil.WriteFile("machine.go", formatted, 0666)
+	m, err := spec.Parse(*in, txt)
 	if err != nil {
 		return err
 	}
+
+	stem := strings.TrimSuffix(*out, ".go")
+	found := false
+	var stale []string
+	for _, g := range modes {
+		if *mode != "all" && *mode != g.name {
+			continue
+		}
+		found = true
+		generated, err := g.generate(m, n)
+		if err != nil {
+			return err
+		}
+		file := stem + g.suffix
+		if *check {
+			old, err := ioutil.ReadFile(file)
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			if err != nil || !bytes.Equal(old, generated) {
+				stale = append(stale, file)
+			}
+			continue
+		}
+		err = ioutil.WriteFile(file, generated, 0666)
+		if err != nil {
+			return err
+		}
+	}
+	if !found {
+		return fmt.Errorf("unknown mode: %v", *mode)
+	}
+	if len(stale) > 0 {
+		return StaleError{stale}
+	}
 	return nil
 }
 
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// support returns the declarations package parser gives the generated code,
+// from file, renamed for package notes with the parse function ParseNotes and
+// the stream type Lines.
+func support(t *testing.T, file string) []byte {
+	buf, err := os.ReadFile(filepath.Join("..", file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []byte(strings.NewReplacer(
+		"package parser", "package notes",
+		"//go:generate", "// go:generate",
+		"ParseTokens", "ParseNotes",
+		"Stream", "Lines",
+		"stream", "lines",
+	).Replace(string(buf)))
+}
+
+func TestGenerator(t *testing.T) {
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+	// inside the module, so the generated code can import the tokenizer,
+	// but ignored by ./...
+	dir, err := os.MkdirTemp(".", "_notes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, file := range []string{"parser.go", "trace.go", "stream.go"} {
+		err := os.WriteFile(filepath.Join(dir, file), support(t, file), 0666)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run := func(args ...string) {
+		cmd := exec.Command(goTool, args...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("go %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+	flags := []string{"-in", "../machine.txt",
+		"-out", filepath.Join(dir, "notes.go"), "-package", "notes",
+		"-func", "ParseNotes", "-stream", "Lines"}
+	run(append([]string{"run", "."}, flags...)...)
+	run(append([]string{"run", ".", "-check"}, flags...)...)
+	// vet compiles the tests too, so this checks the cover file
+	run("vet", "./"+dir)
+
+	buf, err := os.ReadFile(filepath.Join(dir, "notes.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"func ParseNotes(", "func TraceNotes(", "func (s *Lines) Push(",
+		"callback given to NewLines", "Push returns ErrLinesDone",
+	} {
+		if !strings.Contains(string(buf), expected) {
+			t.Errorf("expected generated code to contain %q", expected)
+		}
+	}
+	if strings.Contains(string(buf), "Stream") {
+		t.Errorf("generated code mentions Stream")
+	}
+}
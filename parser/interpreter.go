@@ -0,0 +1,114 @@
+package parser
+
+import (
+	"io/fs"
+
+	"sethwklein.net/thefile/parser/spec"
+	"sethwklein.net/thefile/tokenizer"
+)
+
+// Machine runs a machine description in the format of machine.txt, loaded at
+// runtime instead of compiled by the generator. It exists for experimenting
+// with other ways of finding pages without a rebuild. Given machine.txt, it
+// finds the same pages as ParseTokens.
+type Machine struct {
+	spec *spec.Spec
+}
+
+// NewMachine returns a Machine running the description in txt. Name is used
+// in errors, which are spec.ErrorList values when the description has
+// problems.
+func NewMachine(name, txt string) (*Machine, error) {
+	s, err := spec.Parse(name, []byte(txt))
+	if err != nil {
+		return nil, err
+	}
+	return &Machine{s}, nil
+}
+
+// LoadMachine is like NewMachine, but reads the description from the named
+// file in fsys.
+func LoadMachine(fsys fs.FS, name string) (*Machine, error) {
+	txt, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	return NewMachine(name, string(txt))
+}
+
+// Spec returns the description m runs.
+func (m *Machine) Spec() *spec.Spec {
+	return m.spec
+}
+
+// Parse is like ParseTokens, but runs m instead of the compiled machine.
+func (m *Machine) Parse(tokens []tokenizer.Token) ([]Page, error) {
+	if len(tokens) < 1 || tokens[len(tokens)-1] != 'e' {
+		return nil, ErrMissingEOF
+	}
+
+	var pages []Page
+	var note int
+	// part returns the part end named in a description.
+	part := func(name string) *int {
+		if name == "note" {
+			return &note
+		}
+		// spec.Parse makes sure there is a page whenever parts are used
+		page := &pages[len(pages)-1]
+		switch name {
+		case "head.low":
+			return &page.Head.Low
+		case "head.high":
+			return &page.Head.High
+		case "body.low":
+			return &page.Body.Low
+		case "body.high":
+			return &page.Body.High
+		}
+		panic("unknown part: " + name)
+	}
+
+	state := m.spec.States[0]
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		var t *spec.Transition
+		if tokenizer.Token(byte(token)) == token {
+			t = state.Transition(byte(token))
+		}
+		if t == nil {
+			return nil, ParseError{i, token, state.Name}
+		}
+
+		for _, action := range t.Actions {
+			value := func() int {
+				if action.Expr.Name == "i" {
+					return i + action.Expr.Offset
+				}
+				return *part(action.Expr.Name) + action.Expr.Offset
+			}
+
+			switch action.Verb {
+			case spec.NewPage:
+				pages = append(pages, Page{})
+			case spec.Set:
+				*part(action.Target) = value()
+			case spec.Empty:
+				page := &pages[len(pages)-1]
+				if action.Target == "head" {
+					page.Head.empty(value())
+				} else {
+					page.Body.empty(value())
+				}
+			case spec.Terminate:
+				return pages, nil
+			}
+		}
+		if t.Final() {
+			break
+		}
+		state = m.spec.State(t.Next)
+	}
+	// spec.Parse makes sure e stops the machine, so this is unreachable
+	return pages, nil
+}
@@ -0,0 +1,60 @@
+package parser
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMachine(t *testing.T) {
+	m, err := LoadMachine(os.DirFS("."), "machine.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inputs := []string{"xe", "tcxe", "octxe", "c"}
+	for _, test := range tests {
+		inputs = append(inputs, strings.Replace(test.input, " ", "", -1))
+	}
+	for _, input := range inputs {
+		tokens := toTokens(input)
+		expected, expectedErr := ParseTokens(tokens)
+		actual, actualErr := m.Parse(tokens)
+		if !reflect.DeepEqual(expected, actual) || expectedErr != actualErr {
+			t.Errorf("\ninput:    %v\nexpected: %v, %v\nactual:   %v, %v",
+				input, expected, expectedErr, actual, actualErr)
+		}
+	}
+}
+
+func TestNewMachine(t *testing.T) {
+	// every line is its own page
+	m, err := NewMachine("lines", `
+I+c: I (new page, head.empty(i), body.low = i, body.high = i + 1)
+I+t: I (new page, head.low = i, head.high = i + 1, body.empty(head.high))
+I+o: I (new page, head.empty(i), body.low = i, body.high = i + 1)
+I+e: - (terminate)
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages, err := m.Parse(toTokens("tce"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Page{{Part{0, 1}, Part{1, 1}}, {Part{1, 1}, Part{1, 2}}}
+	if !reflect.DeepEqual(expected, pages) {
+		t.Errorf("\nexpected: %v\nactual:   %v", expected, pages)
+	}
+
+	for _, txt := range []string{
+		"I+c: J ()",
+		// parts used before there is a page
+		"I+c: I ()\nI+t: I (head.low = i)\nI+o: I ()\nI+e: - (terminate)",
+	} {
+		if _, err := NewMachine("bad", txt); err == nil {
+			t.Errorf("expected error from bad description:\n%s", txt)
+		}
+	}
+}
@@ -1,18 +1,18 @@
 package parser
 
 // AUTOMATICALLY GENERATED! DO NOT EDIT!
-// recreate with: go run generator/generator.go
+// recreate with: go generate
 
 import (
-	"errors"
-
 	"sethwklein.net/thefile/tokenizer"
 )
 
-// Parse finds the pages in tokens.
-func Parse(tokens []tokenizer.Token) (pages []Page) {
-	if len(tokens) < 1 || tokens[len(tokens)-1] != tokenizer.Default.E {
-		panic(errors.New("missing end of file token"))
+// ParseTokens finds the pages in tokens. If tokens is not terminated
+// by an end of file token, it returns ErrMissingEOF. If it finds an
+// unknown token, it returns a ParseError.
+func ParseTokens(tokens []tokenizer.Token) (pages []Page, err error) {
+	if len(tokens) < 1 || tokens[len(tokens)-1] != 'e' {
+		return nil, ErrMissingEOF
 	}
 
 	var head, body *Part
@@ -38,9 +38,9 @@ func Parse(tokens []tokenizer.Token) (pages []Page) {
 		body.Low = i
 		goto Bo
 	case 'e':
-		return pages
+		return pages, nil
 	default:
-		panic(TokenError{tokens[i], i})
+		return nil, ParseError{i, tokens[i], "I"}
 	}
 Ic:
 	i++
@@ -58,9 +58,9 @@ Ic:
 		body.Low = i
 		goto Bo
 	case 'e':
-		return pages
+		return pages, nil
 	default:
-		panic(TokenError{tokens[i], i})
+		return nil, ParseError{i, tokens[i], "Ic"}
 	}
 Icc:
 	i++
@@ -77,9 +77,9 @@ Icc:
 		body.Low = note
 		goto Bo
 	case 'e':
-		return pages
+		return pages, nil
 	default:
-		panic(TokenError{tokens[i], i})
+		return nil, ParseError{i, tokens[i], "Icc"}
 	}
 Q:
 	i++
@@ -96,9 +96,9 @@ Q:
 	case 'e':
 		head.High = i
 		body.empty(i)
-		return pages
+		return pages, nil
 	default:
-		panic(TokenError{tokens[i], i})
+		return nil, ParseError{i, tokens[i], "Q"}
 	}
 Hc:
 	i++
@@ -114,9 +114,9 @@ Hc:
 		goto Bo
 	case 'e':
 		body.empty(head.High)
-		return pages
+		return pages, nil
 	default:
-		panic(TokenError{tokens[i], i})
+		return nil, ParseError{i, tokens[i], "Hc"}
 	}
 Hct:
 	i++
@@ -138,9 +138,9 @@ Hct:
 		head.Low = note
 		head.High = i
 		body.empty(i)
-		return pages
+		return pages, nil
 	default:
-		panic(TokenError{tokens[i], i})
+		return nil, ParseError{i, tokens[i], "Hct"}
 	}
 Hcc:
 	i++
@@ -154,9 +154,9 @@ Hcc:
 		goto Bo
 	case 'e':
 		body.empty(head.High)
-		return pages
+		return pages, nil
 	default:
-		panic(TokenError{tokens[i], i})
+		return nil, ParseError{i, tokens[i], "Hcc"}
 	}
 Hcct:
 	i++
@@ -177,9 +177,9 @@ Hcct:
 		head.Low = note
 		head.High = i
 		body.empty(i)
-		return pages
+		return pages, nil
 	default:
-		panic(TokenError{tokens[i], i})
+		return nil, ParseError{i, tokens[i], "Hcct"}
 	}
 Bo:
 	i++
@@ -192,9 +192,9 @@ Bo:
 		goto Bo
 	case 'e':
 		body.High = i
-		return pages
+		return pages, nil
 	default:
-		panic(TokenError{tokens[i], i})
+		return nil, ParseError{i, tokens[i], "Bo"}
 	}
 Bc:
 	i++
@@ -208,9 +208,9 @@ Bc:
 		goto Bo
 	case 'e':
 		body.High = i - 1
-		return pages
+		return pages, nil
 	default:
-		panic(TokenError{tokens[i], i})
+		return nil, ParseError{i, tokens[i], "Bc"}
 	}
 Bct:
 	i++
@@ -231,8 +231,512 @@ Bct:
 		head.Low = note
 		head.High = i
 		body.empty(i)
-		return pages
+		return pages, nil
+	default:
+		return nil, ParseError{i, tokens[i], "Bct"}
+	}
+}
+
+// TraceTokens is ParseTokens, but also returns a Step for each
+// token read, even if it returns an error.
+func TraceTokens(tokens []tokenizer.Token) (pages []Page, steps []Step, err error) {
+	if len(tokens) < 1 || tokens[len(tokens)-1] != 'e' {
+		return nil, steps, ErrMissingEOF
+	}
+
+	var head, body *Part
+	newPage := func() {
+		pages = append(pages, Page{})
+		head = &pages[len(pages)-1].Head
+		body = &pages[len(pages)-1].Body
+	}
+	var note int
+
+	// I:
+	i := 0
+	switch tokens[i] {
+	case 'c':
+		steps = append(steps, Step{i, tokens[i], "I", "Ic", ""})
+		goto Ic
+	case 't':
+		steps = append(steps, Step{i, tokens[i], "I", "Q", "new page, head.low = i"})
+		newPage()
+		head.Low = i
+		goto Q
+	case 'o':
+		steps = append(steps, Step{i, tokens[i], "I", "Bo", "new page, head.empty(i), body.low = i"})
+		newPage()
+		head.empty(i)
+		body.Low = i
+		goto Bo
+	case 'e':
+		steps = append(steps, Step{i, tokens[i], "I", "-", "terminate"})
+		return pages, steps, nil
+	default:
+		return nil, steps, ParseError{i, tokens[i], "I"}
+	}
+Ic:
+	i++
+	switch tokens[i] {
+	case 'c':
+		steps = append(steps, Step{i, tokens[i], "Ic", "Icc", "note = i"})
+		note = i
+		goto Icc
+	case 't':
+		steps = append(steps, Step{i, tokens[i], "Ic", "Q", "new page, head.low = i"})
+		newPage()
+		head.Low = i
+		goto Q
+	case 'o':
+		steps = append(steps, Step{i, tokens[i], "Ic", "Bo", "new page, head.empty(i), body.low = i"})
+		newPage()
+		head.empty(i)
+		body.Low = i
+		goto Bo
+	case 'e':
+		steps = append(steps, Step{i, tokens[i], "Ic", "-", "terminate"})
+		return pages, steps, nil
+	default:
+		return nil, steps, ParseError{i, tokens[i], "Ic"}
+	}
+Icc:
+	i++
+	switch tokens[i] {
+	case 'c':
+		steps = append(steps, Step{i, tokens[i], "Icc", "Icc", ""})
+		goto Icc
+	case 't':
+		steps = append(steps, Step{i, tokens[i], "Icc", "Q", "new page, head.low = i"})
+		newPage()
+		head.Low = i
+		goto Q
+	case 'o':
+		steps = append(steps, Step{i, tokens[i], "Icc", "Bo", "new page, head.empty(note), body.low = note"})
+		newPage()
+		head.empty(note)
+		body.Low = note
+		goto Bo
+	case 'e':
+		steps = append(steps, Step{i, tokens[i], "Icc", "-", "terminate"})
+		return pages, steps, nil
+	default:
+		return nil, steps, ParseError{i, tokens[i], "Icc"}
+	}
+Q:
+	i++
+	switch tokens[i] {
+	case 'c':
+		steps = append(steps, Step{i, tokens[i], "Q", "Hc", "head.high = i"})
+		head.High = i
+		goto Hc
+	case 't':
+		steps = append(steps, Step{i, tokens[i], "Q", "Q", ""})
+		goto Q
+	case 'o':
+		steps = append(steps, Step{i, tokens[i], "Q", "Bo", "head.high = head.low, body.low = head.low"})
+		head.High = head.Low
+		body.Low = head.Low
+		goto Bo
+	case 'e':
+		steps = append(steps, Step{i, tokens[i], "Q", "-", "head.high = i, body.empty(i), terminate"})
+		head.High = i
+		body.empty(i)
+		return pages, steps, nil
+	default:
+		return nil, steps, ParseError{i, tokens[i], "Q"}
+	}
+Hc:
+	i++
+	switch tokens[i] {
+	case 'c':
+		steps = append(steps, Step{i, tokens[i], "Hc", "Hcc", "body.low = i"})
+		body.Low = i
+		goto Hcc
+	case 't':
+		steps = append(steps, Step{i, tokens[i], "Hc", "Hct", "note = i"})
+		note = i
+		goto Hct
+	case 'o':
+		steps = append(steps, Step{i, tokens[i], "Hc", "Bo", "body.low = i"})
+		body.Low = i
+		goto Bo
+	case 'e':
+		steps = append(steps, Step{i, tokens[i], "Hc", "-", "body.empty(head.high), terminate"})
+		body.empty(head.High)
+		return pages, steps, nil
+	default:
+		return nil, steps, ParseError{i, tokens[i], "Hc"}
+	}
+Hct:
+	i++
+	switch tokens[i] {
+	case 'c':
+		steps = append(steps, Step{i, tokens[i], "Hct", "Hc", "body.empty(head.high), new page, head.low = note, head.high = i"})
+		body.empty(head.High)
+		newPage()
+		head.Low = note
+		head.High = i
+		goto Hc
+	case 't':
+		steps = append(steps, Step{i, tokens[i], "Hct", "Hct", ""})
+		goto Hct
+	case 'o':
+		steps = append(steps, Step{i, tokens[i], "Hct", "Bo", "body.low = note"})
+		body.Low = note
+		goto Bo
+	case 'e':
+		steps = append(steps, Step{i, tokens[i], "Hct", "-", "body.empty(head.high), new page, head.low = note, head.high = i, body.empty(i), terminate"})
+		body.empty(head.High)
+		newPage()
+		head.Low = note
+		head.High = i
+		body.empty(i)
+		return pages, steps, nil
+	default:
+		return nil, steps, ParseError{i, tokens[i], "Hct"}
+	}
+Hcc:
+	i++
+	switch tokens[i] {
+	case 'c':
+		steps = append(steps, Step{i, tokens[i], "Hcc", "Bc", ""})
+		goto Bc
+	case 't':
+		steps = append(steps, Step{i, tokens[i], "Hcc", "Hcct", "note = i"})
+		note = i
+		goto Hcct
+	case 'o':
+		steps = append(steps, Step{i, tokens[i], "Hcc", "Bo", ""})
+		goto Bo
+	case 'e':
+		steps = append(steps, Step{i, tokens[i], "Hcc", "-", "body.empty(head.high), terminate"})
+		body.empty(head.High)
+		return pages, steps, nil
+	default:
+		return nil, steps, ParseError{i, tokens[i], "Hcc"}
+	}
+Hcct:
+	i++
+	switch tokens[i] {
+	case 'c':
+		steps = append(steps, Step{i, tokens[i], "Hcct", "Hc", "body.empty(head.high), new page, head.low = note, head.high = i"})
+		body.empty(head.High)
+		newPage()
+		head.Low = note
+		head.High = i
+		goto Hc
+	case 't':
+		steps = append(steps, Step{i, tokens[i], "Hcct", "Hcct", ""})
+		goto Hcct
+	case 'o':
+		steps = append(steps, Step{i, tokens[i], "Hcct", "Bo", ""})
+		goto Bo
+	case 'e':
+		steps = append(steps, Step{i, tokens[i], "Hcct", "-", "body.empty(head.high), new page, head.low = note, head.high = i, body.empty(i), terminate"})
+		body.empty(head.High)
+		newPage()
+		head.Low = note
+		head.High = i
+		body.empty(i)
+		return pages, steps, nil
+	default:
+		return nil, steps, ParseError{i, tokens[i], "Hcct"}
+	}
+Bo:
+	i++
+	switch tokens[i] {
+	case 'c':
+		steps = append(steps, Step{i, tokens[i], "Bo", "Bc", ""})
+		goto Bc
+	case 't':
+		steps = append(steps, Step{i, tokens[i], "Bo", "Bo", ""})
+		goto Bo
+	case 'o':
+		steps = append(steps, Step{i, tokens[i], "Bo", "Bo", ""})
+		goto Bo
+	case 'e':
+		steps = append(steps, Step{i, tokens[i], "Bo", "-", "body.high = i, terminate"})
+		body.High = i
+		return pages, steps, nil
+	default:
+		return nil, steps, ParseError{i, tokens[i], "Bo"}
+	}
+Bc:
+	i++
+	switch tokens[i] {
+	case 'c':
+		steps = append(steps, Step{i, tokens[i], "Bc", "Bc", ""})
+		goto Bc
+	case 't':
+		steps = append(steps, Step{i, tokens[i], "Bc", "Bct", "note = i"})
+		note = i
+		goto Bct
+	case 'o':
+		steps = append(steps, Step{i, tokens[i], "Bc", "Bo", ""})
+		goto Bo
+	case 'e':
+		steps = append(steps, Step{i, tokens[i], "Bc", "-", "body.high = i - 1, terminate"})
+		body.High = i - 1
+		return pages, steps, nil
+	default:
+		return nil, steps, ParseError{i, tokens[i], "Bc"}
+	}
+Bct:
+	i++
+	switch tokens[i] {
+	case 'c':
+		steps = append(steps, Step{i, tokens[i], "Bct", "Hc", "body.high = note - 1, new page, head.low = note, head.high = i"})
+		body.High = note - 1
+		newPage()
+		head.Low = note
+		head.High = i
+		goto Hc
+	case 't':
+		steps = append(steps, Step{i, tokens[i], "Bct", "Bct", ""})
+		goto Bct
+	case 'o':
+		steps = append(steps, Step{i, tokens[i], "Bct", "Bo", ""})
+		goto Bo
+	case 'e':
+		steps = append(steps, Step{i, tokens[i], "Bct", "-", "body.high = note - 1, new page, head.low = note, head.high = i, body.empty(i), terminate"})
+		body.High = note - 1
+		newPage()
+		head.Low = note
+		head.High = i
+		body.empty(i)
+		return pages, steps, nil
+	default:
+		return nil, steps, ParseError{i, tokens[i], "Bct"}
+	}
+}
+
+const (
+	streamI streamState = iota
+	streamIc
+	streamIcc
+	streamQ
+	streamHc
+	streamHct
+	streamHcc
+	streamHcct
+	streamBo
+	streamBc
+	streamBct
+	streamDone
+)
+
+// Push feeds the next token to s. Completed pages are passed to the
+// callback given to NewStream. If token is invalid, Push returns a
+// ParseError and s is left unchanged. After the end of file token,
+// Push returns ErrStreamDone.
+func (s *Stream) Push(token tokenizer.Token) error {
+	i := s.i
+	head, body := &s.page.Head, &s.page.Body
+	switch s.state {
+	case streamI:
+		switch token {
+		case 'c':
+			s.state = streamIc
+		case 't':
+			s.newPage()
+			head.Low = i
+			s.state = streamQ
+		case 'o':
+			s.newPage()
+			head.empty(i)
+			body.Low = i
+			s.state = streamBo
+		case 'e':
+			s.terminate()
+		default:
+			return ParseError{i, token, "I"}
+		}
+	case streamIc:
+		switch token {
+		case 'c':
+			s.note = i
+			s.state = streamIcc
+		case 't':
+			s.newPage()
+			head.Low = i
+			s.state = streamQ
+		case 'o':
+			s.newPage()
+			head.empty(i)
+			body.Low = i
+			s.state = streamBo
+		case 'e':
+			s.terminate()
+		default:
+			return ParseError{i, token, "Ic"}
+		}
+	case streamIcc:
+		switch token {
+		case 'c':
+			s.state = streamIcc
+		case 't':
+			s.newPage()
+			head.Low = i
+			s.state = streamQ
+		case 'o':
+			s.newPage()
+			head.empty(s.note)
+			body.Low = s.note
+			s.state = streamBo
+		case 'e':
+			s.terminate()
+		default:
+			return ParseError{i, token, "Icc"}
+		}
+	case streamQ:
+		switch token {
+		case 'c':
+			head.High = i
+			s.state = streamHc
+		case 't':
+			s.state = streamQ
+		case 'o':
+			head.High = head.Low
+			body.Low = head.Low
+			s.state = streamBo
+		case 'e':
+			head.High = i
+			body.empty(i)
+			s.terminate()
+		default:
+			return ParseError{i, token, "Q"}
+		}
+	case streamHc:
+		switch token {
+		case 'c':
+			body.Low = i
+			s.state = streamHcc
+		case 't':
+			s.note = i
+			s.state = streamHct
+		case 'o':
+			body.Low = i
+			s.state = streamBo
+		case 'e':
+			body.empty(head.High)
+			s.terminate()
+		default:
+			return ParseError{i, token, "Hc"}
+		}
+	case streamHct:
+		switch token {
+		case 'c':
+			body.empty(head.High)
+			s.newPage()
+			head.Low = s.note
+			head.High = i
+			s.state = streamHc
+		case 't':
+			s.state = streamHct
+		case 'o':
+			body.Low = s.note
+			s.state = streamBo
+		case 'e':
+			body.empty(head.High)
+			s.newPage()
+			head.Low = s.note
+			head.High = i
+			body.empty(i)
+			s.terminate()
+		default:
+			return ParseError{i, token, "Hct"}
+		}
+	case streamHcc:
+		switch token {
+		case 'c':
+			s.state = streamBc
+		case 't':
+			s.note = i
+			s.state = streamHcct
+		case 'o':
+			s.state = streamBo
+		case 'e':
+			body.empty(head.High)
+			s.terminate()
+		default:
+			return ParseError{i, token, "Hcc"}
+		}
+	case streamHcct:
+		switch token {
+		case 'c':
+			body.empty(head.High)
+			s.newPage()
+			head.Low = s.note
+			head.High = i
+			s.state = streamHc
+		case 't':
+			s.state = streamHcct
+		case 'o':
+			s.state = streamBo
+		case 'e':
+			body.empty(head.High)
+			s.newPage()
+			head.Low = s.note
+			head.High = i
+			body.empty(i)
+			s.terminate()
+		default:
+			return ParseError{i, token, "Hcct"}
+		}
+	case streamBo:
+		switch token {
+		case 'c':
+			s.state = streamBc
+		case 't':
+			s.state = streamBo
+		case 'o':
+			s.state = streamBo
+		case 'e':
+			body.High = i
+			s.terminate()
+		default:
+			return ParseError{i, token, "Bo"}
+		}
+	case streamBc:
+		switch token {
+		case 'c':
+			s.state = streamBc
+		case 't':
+			s.note = i
+			s.state = streamBct
+		case 'o':
+			s.state = streamBo
+		case 'e':
+			body.High = i - 1
+			s.terminate()
+		default:
+			return ParseError{i, token, "Bc"}
+		}
+	case streamBct:
+		switch token {
+		case 'c':
+			body.High = s.note - 1
+			s.newPage()
+			head.Low = s.note
+			head.High = i
+			s.state = streamHc
+		case 't':
+			s.state = streamBct
+		case 'o':
+			s.state = streamBo
+		case 'e':
+			body.High = s.note - 1
+			s.newPage()
+			head.Low = s.note
+			head.High = i
+			body.empty(i)
+			s.terminate()
+		default:
+			return ParseError{i, token, "Bct"}
+		}
 	default:
-		panic(TokenError{tokens[i], i})
+		return ErrStreamDone
 	}
+	s.i++
+	return nil
 }
@@ -0,0 +1,285 @@
+package parser
+
+// AUTOMATICALLY GENERATED! DO NOT EDIT!
+// recreate with: go generate
+
+import (
+	"sethwklein.net/thefile/tokenizer"
+)
+
+// coverage counts the transitions taken by coverParseTokens,
+// keyed like "Q+c".
+var coverage = make(map[string]int)
+
+// coverParseTokens is ParseTokens, but counts transitions in
+// coverage.
+func coverParseTokens(tokens []tokenizer.Token) (pages []Page, err error) {
+	if len(tokens) < 1 || tokens[len(tokens)-1] != 'e' {
+		return nil, ErrMissingEOF
+	}
+
+	var head, body *Part
+	newPage := func() {
+		pages = append(pages, Page{})
+		head = &pages[len(pages)-1].Head
+		body = &pages[len(pages)-1].Body
+	}
+	var note int
+
+	// I:
+	i := 0
+	switch tokens[i] {
+	case 'c':
+		coverage["I+c"]++
+		goto Ic
+	case 't':
+		coverage["I+t"]++
+		newPage()
+		head.Low = i
+		goto Q
+	case 'o':
+		coverage["I+o"]++
+		newPage()
+		head.empty(i)
+		body.Low = i
+		goto Bo
+	case 'e':
+		coverage["I+e"]++
+		return pages, nil
+	default:
+		return nil, ParseError{i, tokens[i], "I"}
+	}
+Ic:
+	i++
+	switch tokens[i] {
+	case 'c':
+		coverage["Ic+c"]++
+		note = i
+		goto Icc
+	case 't':
+		coverage["Ic+t"]++
+		newPage()
+		head.Low = i
+		goto Q
+	case 'o':
+		coverage["Ic+o"]++
+		newPage()
+		head.empty(i)
+		body.Low = i
+		goto Bo
+	case 'e':
+		coverage["Ic+e"]++
+		return pages, nil
+	default:
+		return nil, ParseError{i, tokens[i], "Ic"}
+	}
+Icc:
+	i++
+	switch tokens[i] {
+	case 'c':
+		coverage["Icc+c"]++
+		goto Icc
+	case 't':
+		coverage["Icc+t"]++
+		newPage()
+		head.Low = i
+		goto Q
+	case 'o':
+		coverage["Icc+o"]++
+		newPage()
+		head.empty(note)
+		body.Low = note
+		goto Bo
+	case 'e':
+		coverage["Icc+e"]++
+		return pages, nil
+	default:
+		return nil, ParseError{i, tokens[i], "Icc"}
+	}
+Q:
+	i++
+	switch tokens[i] {
+	case 'c':
+		coverage["Q+c"]++
+		head.High = i
+		goto Hc
+	case 't':
+		coverage["Q+t"]++
+		goto Q
+	case 'o':
+		coverage["Q+o"]++
+		head.High = head.Low
+		body.Low = head.Low
+		goto Bo
+	case 'e':
+		coverage["Q+e"]++
+		head.High = i
+		body.empty(i)
+		return pages, nil
+	default:
+		return nil, ParseError{i, tokens[i], "Q"}
+	}
+Hc:
+	i++
+	switch tokens[i] {
+	case 'c':
+		coverage["Hc+c"]++
+		body.Low = i
+		goto Hcc
+	case 't':
+		coverage["Hc+t"]++
+		note = i
+		goto Hct
+	case 'o':
+		coverage["Hc+o"]++
+		body.Low = i
+		goto Bo
+	case 'e':
+		coverage["Hc+e"]++
+		body.empty(head.High)
+		return pages, nil
+	default:
+		return nil, ParseError{i, tokens[i], "Hc"}
+	}
+Hct:
+	i++
+	switch tokens[i] {
+	case 'c':
+		coverage["Hct+c"]++
+		body.empty(head.High)
+		newPage()
+		head.Low = note
+		head.High = i
+		goto Hc
+	case 't':
+		coverage["Hct+t"]++
+		goto Hct
+	case 'o':
+		coverage["Hct+o"]++
+		body.Low = note
+		goto Bo
+	case 'e':
+		coverage["Hct+e"]++
+		body.empty(head.High)
+		newPage()
+		head.Low = note
+		head.High = i
+		body.empty(i)
+		return pages, nil
+	default:
+		return nil, ParseError{i, tokens[i], "Hct"}
+	}
+Hcc:
+	i++
+	switch tokens[i] {
+	case 'c':
+		coverage["Hcc+c"]++
+		goto Bc
+	case 't':
+		coverage["Hcc+t"]++
+		note = i
+		goto Hcct
+	case 'o':
+		coverage["Hcc+o"]++
+		goto Bo
+	case 'e':
+		coverage["Hcc+e"]++
+		body.empty(head.High)
+		return pages, nil
+	default:
+		return nil, ParseError{i, tokens[i], "Hcc"}
+	}
+Hcct:
+	i++
+	switch tokens[i] {
+	case 'c':
+		coverage["Hcct+c"]++
+		body.empty(head.High)
+		newPage()
+		head.Low = note
+		head.High = i
+		goto Hc
+	case 't':
+		coverage["Hcct+t"]++
+		goto Hcct
+	case 'o':
+		coverage["Hcct+o"]++
+		goto Bo
+	case 'e':
+		coverage["Hcct+e"]++
+		body.empty(head.High)
+		newPage()
+		head.Low = note
+		head.High = i
+		body.empty(i)
+		return pages, nil
+	default:
+		return nil, ParseError{i, tokens[i], "Hcct"}
+	}
+Bo:
+	i++
+	switch tokens[i] {
+	case 'c':
+		coverage["Bo+c"]++
+		goto Bc
+	case 't':
+		coverage["Bo+t"]++
+		goto Bo
+	case 'o':
+		coverage["Bo+o"]++
+		goto Bo
+	case 'e':
+		coverage["Bo+e"]++
+		body.High = i
+		return pages, nil
+	default:
+		return nil, ParseError{i, tokens[i], "Bo"}
+	}
+Bc:
+	i++
+	switch tokens[i] {
+	case 'c':
+		coverage["Bc+c"]++
+		goto Bc
+	case 't':
+		coverage["Bc+t"]++
+		note = i
+		goto Bct
+	case 'o':
+		coverage["Bc+o"]++
+		goto Bo
+	case 'e':
+		coverage["Bc+e"]++
+		body.High = i - 1
+		return pages, nil
+	default:
+		return nil, ParseError{i, tokens[i], "Bc"}
+	}
+Bct:
+	i++
+	switch tokens[i] {
+	case 'c':
+		coverage["Bct+c"]++
+		body.High = note - 1
+		newPage()
+		head.Low = note
+		head.High = i
+		goto Hc
+	case 't':
+		coverage["Bct+t"]++
+		goto Bct
+	case 'o':
+		coverage["Bct+o"]++
+		goto Bo
+	case 'e':
+		coverage["Bct+e"]++
+		body.High = note - 1
+		newPage()
+		head.Low = note
+		head.High = i
+		body.empty(i)
+		return pages, nil
+	default:
+		return nil, ParseError{i, tokens[i], "Bct"}
+	}
+}
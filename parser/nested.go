@@ -0,0 +1,72 @@
+package parser
+
+import (
+	"sethwklein.net/thefile/tokenizer"
+)
+
+// Node is a page found by ParseNested and the pages in its body.
+type Node struct {
+	Page
+	Children []Node
+}
+
+// ParseNested is like ParseTokens, but also finds child pages in bodies.
+// Depths gives the depth of each title line: 0 for pages, 1 for their
+// children, and so on. Titles deeper than a page are read as other when
+// finding it, and then as titles when its body is parsed for children. Lines
+// in a body before its first child belong to the parent only.
+func ParseNested(tokens []tokenizer.Token, depths []int) ([]Node, error) {
+	if len(tokens) < 1 || tokens[len(tokens)-1] != 'e' {
+		return nil, ErrMissingEOF
+	}
+	return parseLevel(tokens, depths, 0, len(tokens)-1, 0)
+}
+
+// parseLevel finds the pages of the given depth in lines low to high.
+func parseLevel(tokens []tokenizer.Token, depths []int, low, high, depth int) ([]Node, error) {
+	level := make([]tokenizer.Token, high-low+1)
+	found := false
+	for i := low; i < high; i++ {
+		token := tokens[i]
+		if token == 't' {
+			if depths[i] == depth {
+				found = true
+			} else {
+				token = 'o'
+			}
+		}
+		level[i-low] = token
+	}
+	level[high-low] = 'e'
+	if depth > 0 && !found {
+		return nil, nil
+	}
+
+	parsed, err := ParseTokens(level)
+	if err != nil {
+		if pe, ok := err.(ParseError); ok {
+			pe.Index += low
+			err = pe
+		}
+		return nil, err
+	}
+
+	var nodes []Node
+	for _, page := range parsed {
+		if depth > 0 && page.Head.High == page.Head.Low {
+			// before the first child, so part of the parent
+			continue
+		}
+		page.Head.Low += low
+		page.Head.High += low
+		page.Body.Low += low
+		page.Body.High += low
+		children, err := parseLevel(tokens, depths, page.Body.Low,
+			page.Body.High, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, Node{page, children})
+	}
+	return nodes, nil
+}
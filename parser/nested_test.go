@@ -0,0 +1,50 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseNested(t *testing.T) {
+	// without deeper titles, the pages are the same
+	for _, test := range tests {
+		tokens := toTokens(strings.Replace(test.input, " ", "", -1))
+		expected := Parse(tokens)
+		nodes, err := ParseNested(tokens, make([]int, len(tokens)))
+		var actual []Page
+		for _, node := range nodes {
+			if node.Children != nil {
+				t.Errorf("%v: unexpected children: %v", test.input, node.Children)
+			}
+			actual = append(actual, node.Page)
+		}
+		if err != nil || !reflect.DeepEqual(expected, actual) {
+			t.Errorf("\ninput:    %v\nexpected: %v\nactual:   %v, %v",
+				test.input, expected, actual, err)
+		}
+	}
+
+	//                  0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5
+	tokens := toTokens(strings.Replace("t c o c t c o c t c o c t c o e", " ", "", -1))
+	depths := []int{0, 0, 0, 0, 1, 0, 0, 0, 2, 0, 0, 0, 1, 0, 0, 0}
+	expected := []Node{{
+		Page{Part{0, 1}, Part{2, 15}},
+		[]Node{
+			{
+				Page{Part{4, 5}, Part{6, 11}},
+				[]Node{{Page{Part{8, 9}, Part{10, 11}}, nil}},
+			},
+			{Page{Part{12, 13}, Part{14, 15}}, nil},
+		},
+	}}
+	actual, err := ParseNested(tokens, depths)
+	if err != nil || !reflect.DeepEqual(expected, actual) {
+		t.Errorf("\nexpected: %v\nactual:   %v, %v", expected, actual, err)
+	}
+
+	_, err = ParseNested(toTokens("tcoctcxe"), []int{0, 0, 0, 0, 1, 0, 0, 0})
+	if expected := (ParseError{6, 'x', "Bc"}); err != expected {
+		t.Errorf("\nexpected: %v\nactual:   %v", expected, err)
+	}
+}
@@ -0,0 +1,106 @@
+package parser
+
+import (
+	"sync"
+
+	"sethwklein.net/thefile/tokenizer"
+)
+
+// resync returns the index of the first head at or after i, or end if there
+// are none before end, the index of the end of file token.
+//
+// A head is a run of titles after a clear and before a clear or the end of
+// the file. Whatever state the machine is in before it, it finishes the
+// previous page the same way, whether the head follows or the file ends, and
+// then is in the same state it would be in after finding the head from
+// the initial state. That makes it safe to parse the tokens before and after
+// it separately.
+func resync(tokens []tokenizer.Token, i, end int) int {
+	if i < 1 {
+		i = 1
+	}
+	for ; i < end; i++ {
+		if tokens[i] != 't' || tokens[i-1] != 'c' {
+			continue
+		}
+		j := i
+		for j < end && tokens[j] == 't' {
+			j++
+		}
+		if j == end || tokens[j] == 'c' {
+			return i
+		}
+		i = j
+	}
+	return end
+}
+
+// ParseParallel is like ParseTokens, but splits tokens into as many as n
+// chunks at heads and parses them concurrently. The pages are the same as
+// those ParseTokens finds, and so are any errors.
+func ParseParallel(tokens []tokenizer.Token, n int) ([]Page, error) {
+	if len(tokens) < 1 || tokens[len(tokens)-1] != 'e' {
+		return nil, ErrMissingEOF
+	}
+	end := len(tokens) - 1
+	if n < 2 || end < n {
+		return ParseTokens(tokens)
+	}
+	for _, token := range tokens[:end] {
+		if token == 'e' {
+			// ParseTokens stops there, but the chunks after it wouldn't
+			return ParseTokens(tokens)
+		}
+	}
+
+	splits := []int{0}
+	for k := 1; k < n; k++ {
+		split := resync(tokens, k*end/n, end)
+		if split > splits[len(splits)-1] && split < end {
+			splits = append(splits, split)
+		}
+	}
+	splits = append(splits, end)
+
+	results := make([][]Page, len(splits)-1)
+	errs := make([]error, len(splits)-1)
+	var wg sync.WaitGroup
+	for k := range results {
+		wg.Add(1)
+		go func(k int) {
+			defer wg.Done()
+			low, high := splits[k], splits[k+1]
+			chunk := make([]tokenizer.Token, high-low+1)
+			copy(chunk, tokens[low:high])
+			chunk[high-low] = tokens[end]
+			results[k], errs[k] = ParseTokens(chunk)
+		}(k)
+	}
+	wg.Wait()
+
+	total := 0
+	for k, err := range errs {
+		if err != nil {
+			// the state in the error may depend on earlier chunks
+			return ParseTokens(tokens)
+		}
+		total += len(results[k])
+	}
+
+	pages := make([]Page, 0, total)
+	for k, chunk := range results {
+		low := splits[k]
+		for _, page := range chunk {
+			page.Head.Low += low
+			page.Head.High += low
+			page.Body.Low += low
+			page.Body.High += low
+			pages = append(pages, page)
+		}
+	}
+	if len(pages) < 1 {
+		// ParseTokens returns nil for no pages
+		return nil, nil
+	}
+	return pages, nil
+}
@@ -0,0 +1,67 @@
+package parser
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"sethwklein.net/thefile/tokenizer"
+)
+
+// benchmarkTokens returns a long, random token string.
+func benchmarkTokens() []tokenizer.Token {
+	r := rand.New(rand.NewSource(1))
+	tokens := make([]tokenizer.Token, 1000000)
+	for i := range tokens {
+		// mostly bodies, with some heads
+		tokens[i] = tokenizer.Token("cccttoooooo"[r.Intn(11)])
+	}
+	tokens[len(tokens)-1] = 'e'
+	return tokens
+}
+
+func checkParallel(t *testing.T, tokens []tokenizer.Token, n int) bool {
+	expected, expectedErr := ParseTokens(tokens)
+	actual, actualErr := ParseParallel(tokens, n)
+	if reflect.DeepEqual(expected, actual) && expectedErr == actualErr {
+		return true
+	}
+	t.Errorf("\ninput:    %v\nchunks:   %d\nexpected: %v, %v\nactual:   %v, %v",
+		tokensToString(tokens), n, expected, expectedErr, actual, actualErr)
+	return false
+}
+
+func TestParseParallel(t *testing.T) {
+	// e before the end stops ParseTokens
+	enumerate("ctoxe", 7, func(tokens []tokenizer.Token) bool {
+		for n := 1; n <= 4; n++ {
+			if !checkParallel(t, tokens, n) {
+				return false
+			}
+		}
+		return true
+	})
+
+	checkParallel(t, toTokens("tcoeeoocccctcoooooctcooooe"), 4)
+
+	tokens := benchmarkTokens()
+	for _, n := range []int{2, 3, 8, 64} {
+		checkParallel(t, tokens, n)
+	}
+}
+
+func BenchmarkParseTokens(b *testing.B) {
+	tokens := benchmarkTokens()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ParseTokens(tokens)
+	}
+}
+
+func BenchmarkParseParallel(b *testing.B) {
+	tokens := benchmarkTokens()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ParseParallel(tokens, 8)
+	}
+}
@@ -2,13 +2,17 @@
 //
 // A normal page is /t+(co+)*c/, although technically the last clear is not
 // part of the page. There are many corner cases, but those are documented
-// only in the tests.
+// only in the tests. The reference in reference_test.go states them as rules.
 //
-// This package expects 'a' to be tokenized as 't', in contrast to what the
-// tokenizer package produces by default.
+// ParseTokens expects 'a' to be tokenized as 't', in contrast to what the
+// tokenizer package produces by default. ParseWith reads tokens from other
+// tokenizer configurations, including the default.
 package parser
 
+//go:generate go run ./generator
+
 import (
+	"errors"
 	"fmt"
 
 	"sethwklein.net/thefile/tokenizer"
@@ -32,6 +36,37 @@ type Page struct {
 	Head, Body Part
 }
 
+// ErrMissingEOF is returned by ParseTokens when the tokens don't end with an
+// end of file token.
+var ErrMissingEOF = errors.New("missing end of file token")
+
+// ParseError represents an invalid token given for a certain line, along with
+// the name of the machine state, from machine.txt, that rejected it.
+type ParseError struct {
+	Index int
+	Token tokenizer.Token
+	State string
+}
+
+// Error satisfies the error interface.
+func (err ParseError) Error() string {
+	return fmt.Sprintf("bad token, %c, for line %d in state %s", err.Token,
+		err.Index+1, err.State)
+}
+
+// Parse is like ParseTokens, but panics instead of returning an error. It
+// panics with ErrMissingEOF or a TokenError.
+func Parse(tokens []tokenizer.Token) []Page {
+	pages, err := ParseTokens(tokens)
+	if pe, ok := err.(ParseError); ok {
+		panic(TokenError{pe.Token, pe.Index})
+	}
+	if err != nil {
+		panic(err)
+	}
+	return pages
+}
+
 // TokenError represents an invalid token given for a certain line. It is
 // used by Parse when panicking.
 type TokenError struct {
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 
 	"sethwklein.net/thefile/tokenizer"
@@ -105,6 +106,14 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func toTokens(s string) []tokenizer.Token {
+	var tokens []tokenizer.Token
+	for _, t := range s {
+		tokens = append(tokens, tokenizer.Token(t))
+	}
+	return tokens
+}
+
 func tokensToString(tokens []tokenizer.Token) string {
 	var buf []byte
 	buf = append(buf, '{')
@@ -186,3 +195,87 @@ func TestTokenError(t *testing.T) {
 		t.Errorf("unexpected TokenError string\nexpected %v\nactual: %v", expected, actual)
 	}
 }
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		tokens string
+		state  string
+	}{
+		{"xe", "I"},
+		{"cxe", "Ic"},
+		{"ccxe", "Icc"},
+		{"txe", "Q"},
+		{"tcxe", "Hc"},
+		{"tctxe", "Hct"},
+		{"tccxe", "Hcc"},
+		{"tcctxe", "Hcct"},
+		{"oxe", "Bo"},
+		{"ocxe", "Bc"},
+		{"octxe", "Bct"},
+	}
+	for _, test := range tests {
+		tokens := toTokens(test.tokens)
+		pages, err := ParseTokens(tokens)
+		expected := ParseError{len(tokens) - 2, 'x', test.state}
+		if pages != nil || err != expected {
+			t.Errorf("\ninput:    %v\nexpected: %v\nactual:   %v, %v",
+				test.tokens, expected, pages, err)
+		}
+	}
+	if _, err := ParseTokens([]tokenizer.Token{'c'}); err != ErrMissingEOF {
+		t.Errorf("expected ErrMissingEOF, got: %v", err)
+	}
+}
+
+func TestStream(t *testing.T) {
+	for _, test := range tests {
+		tokens := toTokens(strings.Replace(test.input, " ", "", -1))
+		expected := Parse(tokens)
+		var actual []Page
+		s := NewStream(func(page Page) {
+			actual = append(actual, page)
+		})
+		for _, token := range tokens {
+			if err := s.Push(token); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !s.Done() {
+			t.Errorf("stream not done after input: %v", test.input)
+		}
+		if !reflect.DeepEqual(expected, actual) {
+			t.Errorf("\ninput:    %v\nexpected: %v\nactual:   %v",
+				test.input, expected, actual)
+		}
+		if err := s.Push('c'); err != ErrStreamDone {
+			t.Errorf("expected ErrStreamDone, got: %v", err)
+		}
+	}
+}
+
+func TestTraceTokens(t *testing.T) {
+	for _, test := range tests {
+		tokens := toTokens(strings.Replace(test.input, " ", "", -1))
+		pages, steps, err := TraceTokens(tokens)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := Parse(tokens); !reflect.DeepEqual(expected, pages) {
+			t.Errorf("\ninput:    %v\nexpected: %v\nactual:   %v",
+				test.input, expected, pages)
+		}
+		if len(steps) != len(tokens) {
+			t.Errorf("\ninput: %v\n%d steps for %d tokens", test.input,
+				len(steps), len(tokens))
+		}
+	}
+
+	_, steps, err := TraceTokens(toTokens("tcxe"))
+	if err == nil || len(steps) != 2 {
+		t.Errorf("expected two steps and an error, got: %v, %v", steps, err)
+	}
+	expected := "Q+c: Hc (head.high = i)"
+	if actual := steps[1].String(); actual != expected {
+		t.Errorf("\nexpected: %v\nactual:   %v", expected, actual)
+	}
+}
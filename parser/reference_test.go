@@ -0,0 +1,141 @@
+package parser
+
+import (
+	"flag"
+	"reflect"
+	"testing"
+
+	"sethwklein.net/thefile/tokenizer"
+)
+
+var maxLength = flag.Int("maxlen", 10,
+	"longest token string, not counting e, checked against the reference")
+
+// reference finds pages the obvious way instead of with the machine. It
+// exists to check the machine, so it must not share code with it.
+//
+// A head is a run of titles with a clear or the beginning of the file before
+// it and a clear or the end of the file after it. A body is everything between
+// its head and the next head, less the clear after its head and the clear
+// before the next head or at the end of the file. Empty bodies are placed at
+// the end of their head.
+//
+// Anything before the first head, less the first line if it's clear and any
+// trailing clear, is a body with an empty head, unless it is all clears. If
+// it starts with clears and then a title, those clears are left out too.
+func reference(tokens []tokenizer.Token) []Page {
+	end := len(tokens) - 1 // index of e
+
+	// find the heads
+	var heads []Part
+	for i := 0; i < end; i++ {
+		if tokens[i] != 't' || (i > 0 && tokens[i-1] != 'c') {
+			continue
+		}
+		j := i
+		for j < end && tokens[j] == 't' {
+			j++
+		}
+		if j == end || tokens[j] == 'c' {
+			heads = append(heads, Part{i, j})
+		}
+		i = j
+	}
+
+	// body returns the body from low up to the next head, which starts at
+	// next, or the end of the file.
+	body := func(low, next int) (Part, bool) {
+		high := next - 1
+		if next == end && (end < 1 || tokens[end-1] != 'c') {
+			high = end
+		}
+		if high <= low {
+			return Part{}, false
+		}
+		return Part{low, high}, true
+	}
+
+	var pages []Page
+
+	first := end
+	if len(heads) > 0 {
+		first = heads[0].Low
+	}
+	low := 0
+	if end > 0 && tokens[0] == 'c' {
+		low = 1
+	}
+	for low < first && tokens[low] == 'c' {
+		low++
+	}
+	if low < first && tokens[low] == 'o' {
+		// clears before other are kept, except the very first
+		low = 0
+		if tokens[0] == 'c' {
+			low = 1
+		}
+	}
+	if low < first {
+		if b, ok := body(low, first); ok {
+			pages = append(pages, Page{Part{b.Low, b.Low}, b})
+		}
+	}
+
+	for n, head := range heads {
+		next := end
+		if n+1 < len(heads) {
+			next = heads[n+1].Low
+		}
+		b, ok := body(head.High+1, next)
+		if !ok {
+			b = Part{head.High, head.High}
+		}
+		pages = append(pages, Page{head, b})
+	}
+	return pages
+}
+
+func TestReference(t *testing.T) {
+	for _, test := range tests {
+		var input []tokenizer.Token
+		for _, t := range test.input {
+			if t != ' ' {
+				input = append(input, tokenizer.Token(t))
+			}
+		}
+		expected := Parse(input)
+		actual := reference(input)
+		if !reflect.DeepEqual(expected, actual) {
+			t.Errorf("\ninput:     %v\nparse:     %v\nreference: %v",
+				test.input, expected, actual)
+		}
+	}
+}
+
+// TestExhaustive checks Parse against reference for every token string up to
+// -maxlen long. Strings are checked shortest first, and only failures of the
+// shortest failing length are reported, so those are minimal.
+func TestExhaustive(t *testing.T) {
+	const reportLimit = 10
+	failures, failedLength := 0, 0
+	enumerate("cto", *maxLength, func(tokens []tokenizer.Token) bool {
+		if failures > 0 && len(tokens) > failedLength {
+			// only report the shortest
+			return false
+		}
+		expected := Parse(tokens)
+		actual := reference(tokens)
+		if !reflect.DeepEqual(expected, actual) {
+			failures++
+			failedLength = len(tokens)
+			if failures <= reportLimit {
+				t.Errorf("\ninput:     %v\nparse:     %v\nreference: %v",
+					tokensToString(tokens), expected, actual)
+			}
+		}
+		return true
+	})
+	if failures > reportLimit {
+		t.Errorf("%d more counterexamples of the same length", failures-reportLimit)
+	}
+}
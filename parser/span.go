@@ -0,0 +1,77 @@
+package parser
+
+import (
+	"sethwklein.net/thefile/tokenizer"
+)
+
+// SpanKind tells what a Span is.
+type SpanKind int
+
+const (
+	// HeadSpan is a page's head.
+	HeadSpan SpanKind = iota
+	// BodySpan is a page's body.
+	BodySpan
+	// ClearSpan is lines that are not part of any page: the clear between
+	// a head and its body, the clears between pages, and any clears at the
+	// beginning and end of the file.
+	ClearSpan
+)
+
+// String returns the kind's name.
+func (kind SpanKind) String() string {
+	switch kind {
+	case HeadSpan:
+		return "head"
+	case BodySpan:
+		return "body"
+	case ClearSpan:
+		return "clear"
+	}
+	return "unknown"
+}
+
+// Span is a run of lines, along with what they are.
+type Span struct {
+	Kind SpanKind
+
+	// Page is the index of the page the span is part of. For a ClearSpan,
+	// it is the page the clears are in or before, or len(pages) at the end
+	// of the file.
+	Page int
+
+	Part
+}
+
+// Spans returns spans covering all n lines of the file pages were found in,
+// each line exactly once, in order. Empty parts have no span.
+func Spans(pages []Page, n int) []Span {
+	var spans []Span
+	at := 0
+	add := func(kind SpanKind, page int, part Part) {
+		if part.Low > at {
+			spans = append(spans, Span{ClearSpan, page, Part{at, part.Low}})
+			at = part.Low
+		}
+		if part.High > part.Low {
+			spans = append(spans, Span{kind, page, part})
+			at = part.High
+		}
+	}
+	for i, page := range pages {
+		add(HeadSpan, i, page.Head)
+		add(BodySpan, i, page.Body)
+	}
+	add(ClearSpan, len(pages), Part{n, n})
+	return spans
+}
+
+// ParseSpans is like ParseTokens, but also returns the Spans of tokens, less
+// the end of file token.
+func ParseSpans(tokens []tokenizer.Token) ([]Page, []Span, error) {
+	pages, err := ParseTokens(tokens)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pages, Spans(pages, len(tokens)-1), nil
+}
@@ -0,0 +1,36 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSpans(t *testing.T) {
+	for _, test := range tests {
+		tokens := toTokens(strings.Replace(test.input, " ", "", -1))
+		pages, spans, err := ParseSpans(tokens)
+		if err != nil {
+			t.Fatal(err)
+		}
+		at := 0
+		for _, span := range spans {
+			if span.Low != at || span.High <= span.Low {
+				t.Errorf("\ninput: %v\nspans: %v", test.input, spans)
+				break
+			}
+			if span.Kind == ClearSpan {
+				for _, token := range tokens[span.Low:span.High] {
+					if token != 'c' {
+						t.Errorf("\ninput: %v\nnot clear: %v", test.input, span)
+					}
+				}
+			} else if span.Page >= len(pages) {
+				t.Errorf("\ninput: %v\nno page: %v", test.input, span)
+			}
+			at = span.High
+		}
+		if at != len(tokens)-1 {
+			t.Errorf("\ninput: %v\nspans end at %d: %v", test.input, at, spans)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package spec
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ActionText returns the actions of transition as they would be written in a
+// description, without the parentheses.
+func (transition *Transition) ActionText() string {
+	texts := make([]string, len(transition.Actions))
+	for i, action := range transition.Actions {
+		texts[i] = action.Text
+	}
+	return strings.Join(texts, ", ")
+}
+
+func dotQuote(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `"`, `\"`, -1)
+	s = strings.Replace(s, "\n", `\n`, -1)
+	return `"` + s + `"`
+}
+
+// WriteDot writes spec as a Graphviz DOT graph, with a node for each state and
+// an edge for each transition labeled with the token and actions. Transitions
+// that stop the machine go to a node named "-".
+func (spec *Spec) WriteDot(w io.Writer) error {
+	b := bufio.NewWriter(w)
+	b.WriteString("digraph machine {\n")
+	b.WriteString("\trankdir=LR;\n")
+	b.WriteString("\tnode [shape=circle];\n")
+	b.WriteString("\t\"-\" [shape=doublecircle];\n")
+	for i, state := range spec.States {
+		attrs := ""
+		if i == 0 {
+			attrs = "shape=doublecircle, "
+		}
+		fmt.Fprintf(b, "\t%s [%stooltip=%s];\n", dotQuote(state.Name), attrs,
+			dotQuote(state.Comment))
+	}
+	for _, t := range spec.Transitions() {
+		next := t.Next
+		if t.Final() {
+			next = "-"
+		}
+		label := string(t.Token)
+		for _, action := range t.Actions {
+			label += "\n" + action.Text
+		}
+		fmt.Fprintf(b, "\t%s -> %s [label=%s];\n", dotQuote(t.From),
+			dotQuote(next), dotQuote(label))
+	}
+	b.WriteString("}\n")
+	return b.Flush()
+}
+
+func markdownEscape(s string) string {
+	s = strings.Replace(s, "|", `\|`, -1)
+	return strings.Replace(s, "\n", " ", -1)
+}
+
+// WriteMarkdown writes spec as a Markdown table with a row for each state and
+// a column for each token, followed by a list of the state comments.
+func (spec *Spec) WriteMarkdown(w io.Writer) error {
+	b := bufio.NewWriter(w)
+	b.WriteString("| State |")
+	for _, token := range Tokens {
+		fmt.Fprintf(b, " %c |", token)
+	}
+	b.WriteString("\n| --- |")
+	for range Tokens {
+		b.WriteString(" --- |")
+	}
+	b.WriteByte('\n')
+	for _, state := range spec.States {
+		fmt.Fprintf(b, "| %s |", state.Name)
+		for _, t := range state.Transitions {
+			next := t.Next
+			if t.Final() {
+				next = "-"
+			}
+			cell := next
+			if len(t.Actions) > 0 {
+				cell += " (" + t.ActionText() + ")"
+			}
+			fmt.Fprintf(b, " %s |", markdownEscape(cell))
+		}
+		b.WriteByte('\n')
+	}
+	b.WriteByte('\n')
+	for _, state := range spec.States {
+		// comments usually start by naming the state
+		comment := strings.TrimPrefix(state.Comment, state.Name+":")
+		comment = strings.TrimSpace(comment)
+		fmt.Fprintf(b, "- **%s**", state.Name)
+		if comment != "" {
+			fmt.Fprintf(b, ": %s", markdownEscape(comment))
+		}
+		b.WriteByte('\n')
+	}
+	return b.Flush()
+}
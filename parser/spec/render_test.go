@@ -0,0 +1,51 @@
+package spec
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteDot(t *testing.T) {
+	spec, err := Parse("t", []byte(valid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	if err := spec.WriteDot(buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := `digraph machine {
+	rankdir=LR;
+	node [shape=circle];
+	"-" [shape=doublecircle];
+	"A" [shape=doublecircle, tooltip="A: only state"];
+	"A" -> "A" [label="c"];
+	"A" -> "A" [label="t\nnote = i"];
+	"A" -> "A" [label="o"];
+	"A" -> "-" [label="e\nterminate"];
+}
+`
+	if actual := buf.String(); actual != expected {
+		t.Errorf("\nexpected:\n%v\nactual:\n%v", expected, actual)
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	spec, err := Parse("t", []byte(valid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	if err := spec.WriteMarkdown(buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := `| State | c | t | o | e |
+| --- | --- | --- | --- | --- |
+| A | A | A (note = i) | A | - (terminate) |
+
+- **A**: only state
+`
+	if actual := buf.String(); actual != expected {
+		t.Errorf("\nexpected:\n%v\nactual:\n%v", expected, actual)
+	}
+}
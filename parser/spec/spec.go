@@ -0,0 +1,398 @@
+// Package spec reads the state machine descriptions used by the parser, as in
+// parser/machine.txt.
+//
+// Each description line has the form
+//
+//	State+token: Next (action, action, ...)
+//
+// where Next is "-" if the machine stops. Lines starting with # are comments.
+package spec
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Tokens contains the tokens every state must have a transition for, in the
+// order they are conventionally listed.
+const Tokens = "ctoe"
+
+// Spec is a validated machine description.
+type Spec struct {
+	// States are in the order they were first seen. The first is the
+	// initial state.
+	States []*State
+
+	byName map[string]*State
+}
+
+// State returns the state with the given name or nil.
+func (spec *Spec) State(name string) *State {
+	return spec.byName[name]
+}
+
+// Transitions returns all the transitions in the order of States and Tokens.
+func (spec *Spec) Transitions() []*Transition {
+	var transitions []*Transition
+	for _, state := range spec.States {
+		for i := range Tokens {
+			transitions = append(transitions, state.Transitions[i])
+		}
+	}
+	return transitions
+}
+
+// State is a state of the machine.
+type State struct {
+	Name string
+
+	// Comment is the comment block just before the state's first
+	// transition, without the leading "# ".
+	Comment string
+
+	// Line is the line number of the state's first transition.
+	Line int
+
+	// Transitions are indexed like Tokens.
+	Transitions [len(Tokens)]*Transition
+}
+
+// Transition returns the transition from state on token or nil.
+func (state *State) Transition(token byte) *Transition {
+	i := strings.IndexByte(Tokens, token)
+	if i < 0 {
+		return nil
+	}
+	return state.Transitions[i]
+}
+
+// Transition is one line of a machine description.
+type Transition struct {
+	From  string
+	Token byte
+
+	// Next is the name of the next state, or "" if the machine stops.
+	Next string
+
+	Actions []Action
+	Line    int
+}
+
+// String returns the transition's name, such as "Q+c".
+func (transition *Transition) String() string {
+	return fmt.Sprintf("%s+%c", transition.From, transition.Token)
+}
+
+// Final returns whether the machine stops after transition.
+func (transition *Transition) Final() bool {
+	return transition.Next == ""
+}
+
+// Verb is the kind of an Action.
+type Verb int
+
+const (
+	// NewPage appends a new page, which head and body then refer to.
+	NewPage Verb = iota
+	// Set assigns Expr to Target.
+	Set
+	// Empty sets both ends of the Target part to Expr.
+	Empty
+	// Terminate stops the machine.
+	Terminate
+)
+
+// Action is one of the comma separated actions of a transition.
+type Action struct {
+	Verb Verb
+
+	// Target is "note", "head.low", "head.high", "body.low", or
+	// "body.high" for Set, and "head" or "body" for Empty.
+	Target string
+
+	Expr Expr
+
+	// Text is the action as written.
+	Text string
+}
+
+// Expr is a value used by an action: the named value plus Offset.
+type Expr struct {
+	// Name is "i" (the index of the current token), "note", or one of the
+	// part ends.
+	Name   string
+	Offset int
+}
+
+// String returns the expression as it would be written in a description.
+func (expr Expr) String() string {
+	switch {
+	case expr.Offset > 0:
+		return fmt.Sprintf("%s + %d", expr.Name, expr.Offset)
+	case expr.Offset < 0:
+		return fmt.Sprintf("%s - %d", expr.Name, -expr.Offset)
+	}
+	return expr.Name
+}
+
+// Error is a problem with a line of a description.
+type Error struct {
+	Name string
+	Line int
+	Msg  string
+}
+
+// Error satisfies the error interface.
+func (err Error) Error() string {
+	return fmt.Sprintf("%s:%d: %s", err.Name, err.Line, err.Msg)
+}
+
+// ErrorList is all the problems found in a description, sorted by line.
+type ErrorList []Error
+
+// Error satisfies the error interface.
+func (list ErrorList) Error() string {
+	messages := make([]string, len(list))
+	for i, err := range list {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+var (
+	rTransition = regexp.MustCompile(`^([A-Za-z]+)\+([a-z]): ([A-Za-z]+|-) +\((.*)\)$`)
+	rSet        = regexp.MustCompile(`^(note|(?:head|body)\.(?:low|high)) = (.+)$`)
+	rEmpty      = regexp.MustCompile(`^(head|body)\.empty\((.+)\)$`)
+	rExpr       = regexp.MustCompile(`^(i|note|(?:head|body)\.(?:low|high))(?: ([-+]) ([0-9]+))?$`)
+)
+
+func parseExpr(text string) (Expr, bool) {
+	m := rExpr.FindStringSubmatch(text)
+	if m == nil {
+		return Expr{}, false
+	}
+	expr := Expr{Name: m[1]}
+	if m[2] != "" {
+		expr.Offset, _ = strconv.Atoi(m[3])
+		if m[2] == "-" {
+			expr.Offset = -expr.Offset
+		}
+	}
+	return expr, true
+}
+
+func parseAction(text string) (Action, bool) {
+	action := Action{Text: text}
+	switch text {
+	case "new page":
+		action.Verb = NewPage
+		return action, true
+	case "terminate":
+		action.Verb = Terminate
+		return action, true
+	}
+	var ok bool
+	if m := rSet.FindStringSubmatch(text); m != nil {
+		action.Verb = Set
+		action.Target = m[1]
+		action.Expr, ok = parseExpr(m[2])
+		return action, ok
+	}
+	if m := rEmpty.FindStringSubmatch(text); m != nil {
+		action.Verb = Empty
+		action.Target = m[1]
+		action.Expr, ok = parseExpr(m[2])
+		return action, ok
+	}
+	return action, false
+}
+
+// Parse reads the description in txt. Name is used in errors. If there are
+// any problems, it returns an ErrorList describing all of them.
+func Parse(name string, txt []byte) (*Spec, error) {
+	spec := &Spec{byName: make(map[string]*State)}
+	var errs ErrorList
+	report := func(line int, format string, args ...interface{}) {
+		errs = append(errs, Error{name, line, fmt.Sprintf(format, args...)})
+	}
+
+	var comment []string
+	scanner := bufio.NewScanner(bytes.NewReader(txt))
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			comment = append(comment,
+				strings.TrimPrefix(strings.TrimPrefix(line, "#"), " "))
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			comment = nil
+			continue
+		}
+
+		m := rTransition.FindStringSubmatch(line)
+		if m == nil {
+			report(n, "unrecognized line: %s", line)
+			continue
+		}
+		t := &Transition{From: m[1], Token: m[2][0], Next: m[3], Line: n}
+		if t.Next == "-" {
+			t.Next = ""
+		}
+
+		state := spec.byName[t.From]
+		if state == nil {
+			state = &State{
+				Name:    t.From,
+				Comment: strings.Join(comment, "\n"),
+				Line:    n,
+			}
+			spec.byName[t.From] = state
+			spec.States = append(spec.States, state)
+		}
+		comment = nil
+
+		i := strings.IndexByte(Tokens, t.Token)
+		if i < 0 {
+			report(n, "unknown token %c in %s+%c", t.Token, t.From, t.Token)
+			continue
+		}
+		if first := state.Transitions[i]; first != nil {
+			report(n, "duplicate transition %s+%c, first defined at line %d",
+				t.From, t.Token, first.Line)
+			continue
+		}
+		state.Transitions[i] = t
+
+		terminates := false
+		if strings.TrimSpace(m[4]) != "" {
+			for _, text := range strings.Split(m[4], ",") {
+				text = strings.TrimSpace(text)
+				action, ok := parseAction(text)
+				if !ok {
+					report(n, "unknown action %q in %s+%c", text,
+						t.From, t.Token)
+					continue
+				}
+				if terminates {
+					report(n, "action %q after terminate in %s+%c",
+						text, t.From, t.Token)
+				}
+				terminates = terminates || action.Verb == Terminate
+				t.Actions = append(t.Actions, action)
+			}
+		}
+		switch {
+		case t.Token == 'e' && !t.Final():
+			report(n, "%s+e must go to - since there are no tokens after e",
+				t.From)
+		case t.Final() && !terminates:
+			report(n, "%s+%c goes to - without terminate", t.From, t.Token)
+		case !t.Final() && terminates:
+			report(n, "%s+%c terminates but goes to %s", t.From, t.Token,
+				t.Next)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(spec.States) < 1 {
+		report(1, "no states defined")
+	}
+
+	for _, state := range spec.States {
+		for i, t := range state.Transitions {
+			if t == nil {
+				report(state.Line, "state %s has no transition for %c",
+					state.Name, Tokens[i])
+				continue
+			}
+			if !t.Final() && spec.byName[t.Next] == nil {
+				report(t.Line, "%s+%c goes to undefined state %s",
+					t.From, t.Token, t.Next)
+			}
+		}
+	}
+
+	if len(spec.States) > 0 {
+		reached := map[string]bool{spec.States[0].Name: true}
+		queue := []*State{spec.States[0]}
+		for len(queue) > 0 {
+			state := queue[0]
+			queue = queue[1:]
+			for _, t := range state.Transitions {
+				if t == nil || t.Final() || reached[t.Next] {
+					continue
+				}
+				reached[t.Next] = true
+				if next := spec.byName[t.Next]; next != nil {
+					queue = append(queue, next)
+				}
+			}
+		}
+		for _, state := range spec.States {
+			if !reached[state.Name] {
+				report(state.Line, "state %s is unreachable from %s",
+					state.Name, spec.States[0].Name)
+			}
+		}
+	}
+
+	if len(errs) < 1 {
+		checkPages(spec, report)
+	}
+
+	if len(errs) > 0 {
+		sort.SliceStable(errs, func(i, j int) bool {
+			return errs[i].Line < errs[j].Line
+		})
+		return nil, errs
+	}
+	return spec, nil
+}
+
+// usesPage returns whether action reads or writes the current page.
+func usesPage(action Action) bool {
+	switch action.Verb {
+	case Set:
+		return action.Target != "note" ||
+			(action.Expr.Name != "i" && action.Expr.Name != "note")
+	case Empty:
+		return true
+	}
+	return false
+}
+
+// checkPages reports actions that use the current page on a path where no
+// page has been created yet.
+func checkPages(spec *Spec, report func(int, string, ...interface{})) {
+	initial := spec.States[0]
+	noPage := map[string]bool{initial.Name: true}
+	queue := []*State{initial}
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+		for _, t := range state.Transitions {
+			page := false
+			for _, action := range t.Actions {
+				if action.Verb == NewPage {
+					page = true
+				} else if !page && usesPage(action) {
+					report(t.Line, "%s+%c can use %q before any new page",
+						t.From, t.Token, action.Text)
+					break
+				}
+			}
+			if !page && !t.Final() && !noPage[t.Next] {
+				noPage[t.Next] = true
+				queue = append(queue, spec.byName[t.Next])
+			}
+		}
+	}
+}
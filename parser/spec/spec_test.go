@@ -0,0 +1,101 @@
+package spec
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestMachine(t *testing.T) {
+	txt, err := ioutil.ReadFile("../machine.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, err := Parse("machine.txt", txt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec.States[0].Name != "I" {
+		t.Errorf("expected initial state I, got %v", spec.States[0].Name)
+	}
+	if len(spec.Transitions()) != len(spec.States)*len(Tokens) {
+		t.Errorf("missing transitions")
+	}
+	bct := spec.State("Bct").Transition('c')
+	expected := []Action{
+		{Set, "body.high", Expr{"note", -1}, "body.high = note - 1"},
+		{NewPage, "", Expr{}, "new page"},
+		{Set, "head.low", Expr{"note", 0}, "head.low = note"},
+		{Set, "head.high", Expr{"i", 0}, "head.high = i"},
+	}
+	if len(bct.Actions) != len(expected) {
+		t.Fatalf("wrong actions for Bct+c: %v", bct.Actions)
+	}
+	for i := range expected {
+		if bct.Actions[i] != expected[i] {
+			t.Errorf("\nexpected: %v\nactual:   %v", expected[i], bct.Actions[i])
+		}
+	}
+}
+
+const valid = `# A: only state
+A+c: A ()
+A+t: A (note = i)
+A+o: A ()
+A+e: - (terminate)
+`
+
+var errorTests = []struct {
+	txt      string
+	expected string
+}{
+	{valid, ""},
+	{"", "t:1: no states defined"},
+	{strings.Replace(valid, "A+t: A", "A+t A", 1),
+		"t:2: state A has no transition for t\n" +
+			"t:3: unrecognized line: A+t A (note = i)"},
+	{valid + "A+c: A ()\n",
+		"t:6: duplicate transition A+c, first defined at line 2"},
+	{strings.Replace(valid, "A+o: A", "A+o: B", 1),
+		"t:4: A+o goes to undefined state B"},
+	{valid + "B+c: A ()\nB+t: A ()\nB+o: A ()\nB+e: - (terminate)\n",
+		"t:6: state B is unreachable from A"},
+	{strings.Replace(valid, "note = i", "note = j", 1),
+		`t:3: unknown action "note = j" in A+t`},
+	{strings.Replace(valid, "note = i", "new line", 1),
+		`t:3: unknown action "new line" in A+t`},
+	{strings.Replace(valid, "A+e: - (terminate)", "A+e: A ()", 1),
+		"t:5: A+e must go to - since there are no tokens after e"},
+	{strings.Replace(valid, "A+e: - (terminate)", "A+e: - ()", 1),
+		"t:5: A+e goes to - without terminate"},
+	{strings.Replace(valid, "A+c: A ()", "A+c: A (terminate)", 1),
+		"t:2: A+c terminates but goes to A"},
+	{strings.Replace(valid, "note = i", "head.low = i", 1),
+		`t:3: A+t can use "head.low = i" before any new page`},
+	{strings.Replace(valid, "A+o: A ()", "A+o: B ()", 1) +
+		"B+c: B (new page)\nB+t: B (body.high = i)\nB+o: A ()\nB+e: - (terminate)\n",
+		`t:7: B+t can use "body.high = i" before any new page`},
+	{strings.Replace(valid, "A+o: A ()", "A+o: B (new page)", 1) +
+		"B+c: B ()\nB+t: B (body.high = i)\nB+o: A (note = body.high)\nB+e: - (terminate)\n",
+		""},
+	{strings.Replace(valid, "A+o: A ()", "A+o: B ()", 1) +
+		"B+c: B ()\nB+t: B (body.empty(i), new page)\nB+o: A ()\nB+e: - (terminate)\n",
+		`t:7: B+t can use "body.empty(i)" before any new page`},
+	{strings.Replace(valid, "A+c: A ()", "A+x: A ()", 1),
+		"t:2: unknown token x in A+x\n" +
+			"t:2: state A has no transition for c"},
+}
+
+func TestErrors(t *testing.T) {
+	for _, test := range errorTests {
+		_, err := Parse("t", []byte(test.txt))
+		actual := ""
+		if err != nil {
+			actual = err.Error()
+		}
+		if actual != test.expected {
+			t.Errorf("\ninput:\n%v\nexpected:\n%v\nactual:\n%v", test.txt,
+				test.expected, actual)
+		}
+	}
+}
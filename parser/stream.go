@@ -0,0 +1,52 @@
+package parser
+
+import (
+	"errors"
+)
+
+// ErrStreamDone is returned by Stream.Push after the end of file token.
+var ErrStreamDone = errors.New("token after end of file token")
+
+type streamState int
+
+// Stream finds pages in tokens given one at a time, so that pages are
+// available before all the tokens are. It runs the same machine as
+// ParseTokens and finds the same pages.
+type Stream struct {
+	emit  func(Page)
+	state streamState
+	i     int
+	note  int
+
+	// page is the page being built. It is passed to emit when the next page
+	// is started or the end of file token is pushed.
+	page    Page
+	started bool
+}
+
+// NewStream returns a Stream that calls emit with each page once it is
+// complete.
+func NewStream(emit func(Page)) *Stream {
+	return &Stream{emit: emit}
+}
+
+// Done returns whether the end of file token has been pushed.
+func (s *Stream) Done() bool {
+	return s.state == streamDone
+}
+
+func (s *Stream) newPage() {
+	if s.started {
+		s.emit(s.page)
+	}
+	s.page = Page{}
+	s.started = true
+}
+
+func (s *Stream) terminate() {
+	if s.started {
+		s.emit(s.page)
+	}
+	s.started = false
+	s.state = streamDone
+}
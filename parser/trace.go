@@ -0,0 +1,27 @@
+package parser
+
+import (
+	"fmt"
+
+	"sethwklein.net/thefile/tokenizer"
+)
+
+// Step is what the machine did with one token, as recorded by TraceTokens.
+type Step struct {
+	Index int
+	Token tokenizer.Token
+
+	// From is the state the token was read in. To is the state entered, or
+	// "-" if the machine stopped.
+	From, To string
+
+	// Actions are the actions performed, as written in machine.txt.
+	Actions string
+}
+
+// String returns the step in the form "Q+c: Hc (head.high = i)", like a line of
+// machine.txt.
+func (step Step) String() string {
+	return fmt.Sprintf("%s+%c: %s (%s)", step.From, step.Token, step.To,
+		step.Actions)
+}
@@ -5,12 +5,13 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
-	"sethwklein.net/thefile/storage"
+	"sethwklein.net/thefile/thefile"
 )
 
 func commented(buf []byte, count int) error {
@@ -45,7 +46,14 @@ func commented(buf []byte, count int) error {
 }
 
 func mainError() (err error) {
-	buf, err := storage.Load()
+	file := flag.String("file", "", "file to read instead of the one in storage")
+	flag.Parse()
+
+	source := thefile.Storage
+	if *file != "" {
+		source = thefile.FileSource(*file)
+	}
+	buf, err := source.Load()
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,163 @@
+package thefile
+
+import (
+	"bytes"
+	"fmt"
+	"unicode"
+
+	"sethwklein.net/thefile/parser"
+	"sethwklein.net/thefile/tokenizer"
+)
+
+// Unterminated says how a Dialect reads a last line with no newline.
+type Unterminated int
+
+const (
+	// UnterminatedLine reads it like any other line.
+	UnterminatedLine Unterminated = iota
+	// UnterminatedOther never reads it as a title, in case it is still
+	// being written.
+	UnterminatedOther
+)
+
+// Dialect describes how title lines are written, so that notes files written
+// in other conventions can be read.
+type Dialect struct {
+	// Marker starts title lines, as in "----title". It must not be empty
+	// or only whitespace.
+	Marker string
+
+	// AlternateMarker, if not empty, starts alternate title lines. It must
+	// start with Marker, as in "----=".
+	AlternateMarker string
+
+	// TrimSpace trims trailing whitespace from titles.
+	TrimSpace bool
+
+	Unterminated Unterminated
+
+	// Tokenized has the tokenizer package, or the tokenizer given to
+	// WithTokenizer, decide which lines are titles and alternate titles,
+	// instead of Marker and AlternateMarker. The markers still say where
+	// titles start and how to write title lines.
+	Tokenized bool
+}
+
+// DefaultDialect is the dialect of the file. Its lines are tokenized by the
+// tokenizer package.
+var DefaultDialect = Dialect{Marker: "----", TrimSpace: true, Tokenized: true}
+
+// DialectError is returned by Pages and the functions like it when the
+// Dialect given to WithDialect can't be used.
+type DialectError struct {
+	Dialect Dialect
+	Msg     string
+}
+
+// Error satisfies the error interface.
+func (err DialectError) Error() string {
+	return fmt.Sprintf("dialect with marker %q: %s", err.Dialect.Marker, err.Msg)
+}
+
+// check returns a DialectError if d can't be used.
+func (d Dialect) check() error {
+	switch {
+	case len(bytes.TrimSpace([]byte(d.Marker))) < 1:
+		return DialectError{d, "the marker must not be empty or only whitespace"}
+	case d.AlternateMarker == "":
+		return nil
+	case d.AlternateMarker == d.Marker:
+		return DialectError{d, "the alternate marker must differ from the marker"}
+	case !bytes.HasPrefix([]byte(d.AlternateMarker), []byte(d.Marker)):
+		return DialectError{d, "the alternate marker must start with the marker"}
+	}
+	return nil
+}
+
+// Line tokenizes the first line in buf like tokenizer.Tokenizer.Line, with
+// the tokens of parser.DefaultAlphabet, using Marker and AlternateMarker even
+// if d is Tokenized. It returns the token and the length of the line,
+// including its newline. Lines of only whitespace are clear.
+func (d Dialect) Line(buf []byte) (tokenizer.Token, int) {
+	alphabet := parser.DefaultAlphabet
+	if len(buf) < 1 {
+		return alphabet.E, 0
+	}
+	n := bytes.IndexByte(buf, '\n') + 1
+	if n < 1 {
+		n = len(buf)
+		if d.Unterminated == UnterminatedOther {
+			if len(bytes.TrimSpace(buf)) < 1 {
+				return alphabet.C, n
+			}
+			return alphabet.O, n
+		}
+	}
+	line := buf[:n]
+	if len(bytes.TrimSpace(line)) < 1 {
+		return alphabet.C, n
+	}
+	depth := d.depth(line)
+	switch {
+	case depth < 0:
+		return alphabet.O, n
+	case d.AlternateMarker != "" &&
+		bytes.HasPrefix(line[depth:], []byte(d.AlternateMarker)):
+		return alphabet.A, n
+	}
+	return alphabet.T, n
+}
+
+// title returns the title from line, which starts with a marker prefix bytes
+// long and may end with a newline, LF or CRLF.
+func (d Dialect) title(line []byte, prefix int) []byte {
+	line = bytes.TrimSuffix(line, []byte{'\n'})
+	line = bytes.TrimSuffix(line, []byte{'\r'})
+	if len(line) <= prefix {
+		return nil
+	}
+	title := line[prefix:]
+	if d.TrimSpace {
+		title = bytes.TrimRightFunc(title, unicode.IsSpace)
+	}
+	return title
+}
+
+// depth returns the number of extra copies of the first byte of the marker
+// at the start of line, which nested pages use to mark their depth, as in
+// "-----child" for DefaultDialect or "### child" for "## ". It returns -1 if
+// line doesn't start with the marker after them.
+func (d Dialect) depth(line []byte) int {
+	if len(d.Marker) < 1 {
+		return -1
+	}
+	c := d.Marker[0]
+	extra := 0
+	for extra < len(line) && line[extra] == c {
+		extra++
+	}
+	for i := 0; i < len(d.Marker) && d.Marker[i] == c; i++ {
+		extra--
+	}
+	if extra < 0 || !bytes.HasPrefix(line[extra:], []byte(d.Marker)) {
+		return -1
+	}
+	return extra
+}
+
+// unterminatedOther reads a last line with no newline as other, as
+// UnterminatedOther does, for tokenizers that read it like any other line.
+type unterminatedOther struct {
+	tok      lineTokenizer
+	alphabet parser.Alphabet
+}
+
+// Line satisfies the lineTokenizer interface.
+func (t *unterminatedOther) Line(buf []byte) (tokenizer.Token, int) {
+	token, n := t.tok.Line(buf)
+	if n > 0 && n == len(buf) && buf[n-1] != '\n' &&
+		(token == t.alphabet.T || token == t.alphabet.A) {
+		return t.alphabet.O, n
+	}
+	return token, n
+}
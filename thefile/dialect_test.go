@@ -0,0 +1,175 @@
+package thefile
+
+import (
+	"reflect"
+	"testing"
+
+	"sethwklein.net/thefile/tokenizer"
+)
+
+func TestDialectLine(t *testing.T) {
+	d := Dialect{Marker: "## ", AlternateMarker: "## =", TrimSpace: true}
+	tests := []struct {
+		line   string
+		token  tokenizer.Token
+		length int
+	}{
+		{"", 'e', 0},
+		{"\n", 'c', 1},
+		{" \t\nmore", 'c', 3},
+		{"## title\n", 't', 9},
+		{"### nested\n", 't', 11},
+		{"## =alternate\n", 'a', 14},
+		{"#\n", 'o', 2},
+		{"# not a title\n", 'o', 14},
+		{"text ## title\n", 'o', 14},
+		{"## unterminated", 't', 15},
+	}
+	for _, test := range tests {
+		token, length := d.Line([]byte(test.line))
+		if token != test.token || length != test.length {
+			t.Errorf("\ninput:    %q\nexpected: %c %d\nactual:   %c %d",
+				test.line, test.token, test.length, token, length)
+		}
+	}
+
+	d.Unterminated = UnterminatedOther
+	if token, _ := d.Line([]byte("## unterminated")); token != 'o' {
+		t.Errorf("unterminated line read as %c", token)
+	}
+}
+
+func TestDialect(t *testing.T) {
+	buf := []byte("* one  \n* =also one\n\nbody\n\n* two\n\n** child\n\nbody\n\n* three")
+	d := Dialect{Marker: "* ", AlternateMarker: "* =", TrimSpace: true}
+
+	pages, err := NestedPages(WithSource(BytesSource(buf)), WithDialect(d))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var titles [][]string
+	for _, page := range Flatten(pages) {
+		titles = append(titles, page.Tags())
+	}
+	expected := [][]string{{"one", "also one"}, {"two"}, {"child"}, {"three"}}
+	if !reflect.DeepEqual(expected, titles) {
+		t.Errorf("\nexpected: %q\nactual:   %q", expected, titles)
+	}
+	if want, got := []string{"also one"}, pages[0].Alternates(); !reflect.DeepEqual(want, got) {
+		t.Errorf("\nwant: %q\ngot:  %q\n", want, got)
+	}
+
+	// deeper markers are stripped without nesting too
+	pages, err = Pages(WithSource(BytesSource(buf)), WithDialect(d))
+	if err != nil {
+		t.Fatal(err)
+	}
+	titles = nil
+	for _, page := range pages {
+		titles = append(titles, page.Tags())
+	}
+	if !reflect.DeepEqual(expected, titles) {
+		t.Errorf("\nexpected: %q\nactual:   %q", expected, titles)
+	}
+	flat := Dialect{Marker: "## ", TrimSpace: true}
+	pages, err = Pages(WithSource(BytesSource([]byte("## top\n\n### sub\n"))),
+		WithDialect(flat))
+	if err != nil {
+		t.Fatal(err)
+	}
+	titles = nil
+	for _, page := range pages {
+		titles = append(titles, page.Tags())
+	}
+	if expected := [][]string{{"top"}, {"sub"}}; !reflect.DeepEqual(expected, titles) {
+		t.Errorf("\nexpected: %q\nactual:   %q", expected, titles)
+	}
+
+	d.TrimSpace = false
+	pages, err = Pages(WithSource(BytesSource(buf)), WithDialect(d))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name, _ := pages[0].Name(); name != "one  " {
+		t.Errorf("expected untrimmed title, got %q", name)
+	}
+}
+
+func TestUnterminatedTitle(t *testing.T) {
+	pages, _, err := parsePages([]byte("----one\n\nbody\n\n----two"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name, _ := pages[len(pages)-1].Name(); name != "two" {
+		t.Errorf("expected two, got %q", name)
+	}
+}
+
+func TestDialectErrors(t *testing.T) {
+	source := BytesSource([]byte("----one\n\nbody\n"))
+	tests := []Option{
+		WithDialect(Dialect{}),
+		WithDialect(Dialect{Marker: " \t"}),
+		WithDialect(Dialect{Marker: "## ", AlternateMarker: "## "}),
+		WithDialect(Dialect{Marker: "## ", AlternateMarker: "=="}),
+		WithTokenizer(tokenizer.Default),
+	}
+	for i, opt := range tests {
+		opts := []Option{opt}
+		if i == len(tests)-1 {
+			opts = append(opts, WithDialect(Dialect{Marker: "## "}))
+		}
+		if _, err := PagesFrom(source, opts...); !isDialectError(err) {
+			t.Errorf("%d: expected DialectError, got %v", i, err)
+		}
+		if _, err := Pages(append(opts, WithSource(source))...); !isDialectError(err) {
+			t.Errorf("%d: expected DialectError from Pages, got %v", i, err)
+		}
+		if _, _, err := Spans([]byte("----one\n"), opts...); !isDialectError(err) {
+			t.Errorf("%d: expected DialectError from Spans, got %v", i, err)
+		}
+	}
+}
+
+func isDialectError(err error) bool {
+	_, ok := err.(DialectError)
+	return ok
+}
+
+// TestTokenizedDialect checks that changing a field of DefaultDialect other
+// than Tokenized still has the tokenizer find titles.
+func TestTokenizedDialect(t *testing.T) {
+	buf := []byte("----one\n----=two\n\nbody\n\n----three")
+	source := BytesSource(buf)
+	expected, err := PagesFrom(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(expected[0].Alternates()) < 1 {
+		t.Fatalf("no alternates in %q", buf)
+	}
+
+	d := DefaultDialect
+	d.Unterminated = UnterminatedOther
+	upper := tokenizer.Tokenizer{C: 'C', T: 'T', A: 'A', O: 'O', E: 'E'}
+	pages, err := PagesFrom(source, WithDialect(d), WithTokenizer(upper))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 1 {
+		t.Fatalf("expected the unterminated title to be read as other, got %d pages", len(pages))
+	}
+	if want, got := expected[0].Alternates(), pages[0].Alternates(); !reflect.DeepEqual(want, got) {
+		t.Errorf("\nwant: %q\ngot:  %q\n", want, got)
+	}
+
+	d = DefaultDialect
+	d.TrimSpace = false
+	pages, err = PagesFrom(BytesSource([]byte("----one \n\nbody\n")), WithDialect(d))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name, _ := pages[0].Name(); name != "one " {
+		t.Errorf("expected untrimmed title, got %q", name)
+	}
+}
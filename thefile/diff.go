@@ -0,0 +1,256 @@
+package thefile
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+)
+
+// A plain text diff of the whole file is mostly noise, because pages move
+// around. Comparing page by page shows what actually changed.
+
+// Difference describes how a page changed. It is a set of flags, and zero
+// means the page is unchanged.
+type Difference uint
+
+const (
+	// Moved is a page with a different address.
+	Moved Difference = 1 << iota
+	// Retitled is a page with a different name.
+	Retitled
+	// Retagged is a page with different titles after the name, or different
+	// alternate titles.
+	Retagged
+	// BodyEdited is a page with a different body.
+	BodyEdited
+	// Added is a page only in the new pages.
+	Added
+	// Deleted is a page only in the old pages.
+	Deleted
+	// Reordered is a page that is out of order with the other pages. Adding
+	// or deleting a page moves the pages after it, but doesn't reorder
+	// them. Of pages that swapped places, as few as possible are reordered.
+	Reordered
+)
+
+var differenceNames = []string{"moved", "retitled", "retagged", "body edited",
+	"added", "deleted", "reordered"}
+
+func (d Difference) String() string {
+	if d == 0 {
+		return "unchanged"
+	}
+	var names []string
+	for i, name := range differenceNames {
+		if d&(1<<uint(i)) != 0 {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// PageDiff pairs an old page with the new page it became. Old is nil for
+// Added pages and New is nil for Deleted ones.
+type PageDiff struct {
+	Old, New   *Page
+	Difference Difference
+}
+
+// similarity is the least share of body lines two pages must have in common
+// to be matched by body.
+const similarity = 0.5
+
+// Diff matches oldPages to newPages and returns how each changed. Pages are
+// matched first by hash, then by name, and then by how many body lines they
+// share. The result is in the order of newPages, followed by the Deleted
+// pages in the order of oldPages.
+func Diff(oldPages, newPages []*Page) []PageDiff {
+	old := make(map[*Page]*Page)
+	matched := make(map[*Page]bool)
+	pair := func(o, n *Page) {
+		old[n] = o
+		matched[o] = true
+	}
+
+	byHash := make(map[string][]*Page)
+	for _, page := range oldPages {
+		hash := string(page.HashRaw())
+		byHash[hash] = append(byHash[hash], page)
+	}
+	for _, page := range newPages {
+		hash := string(page.HashRaw())
+		if candidates := byHash[hash]; len(candidates) > 0 {
+			byHash[hash] = candidates[1:]
+			pair(candidates[0], page)
+		}
+	}
+
+	byName := make(map[string][]*Page)
+	for _, page := range oldPages {
+		if name, anonymous := page.Name(); !matched[page] && !anonymous {
+			byName[name] = append(byName[name], page)
+		}
+	}
+	for _, page := range newPages {
+		name, anonymous := page.Name()
+		if candidates := byName[name]; old[page] == nil && !anonymous &&
+			len(candidates) > 0 {
+			byName[name] = candidates[1:]
+			pair(candidates[0], page)
+		}
+	}
+
+	matchSimilar(oldPages, newPages, matched, old, pair)
+
+	kept := inOrder(oldPages, newPages, old)
+	diffs := make([]PageDiff, 0, len(newPages))
+	for _, page := range newPages {
+		diffs = append(diffs, diff(old[page], page, !kept[page]))
+	}
+	for _, page := range oldPages {
+		if !matched[page] {
+			diffs = append(diffs, PageDiff{page, nil, Deleted})
+		}
+	}
+	return diffs
+}
+
+// matchSimilar pairs the pages that remain unmatched by the body lines they
+// share, most similar first.
+func matchSimilar(oldPages, newPages []*Page, matched map[*Page]bool, old map[*Page]*Page, pair func(o, n *Page)) {
+	// index the old pages by line, skipping blank lines, which are in
+	// nearly every page and say nothing about which pages are similar
+	withLine := make(map[string][]*Page)
+	size := make(map[*Page]int)
+	for _, page := range oldPages {
+		if matched[page] {
+			continue
+		}
+		lines := bodyLines(page)
+		for line := range lines {
+			withLine[line] = append(withLine[line], page)
+		}
+		size[page] = len(lines)
+	}
+
+	type candidate struct {
+		o, n  *Page
+		score float64
+	}
+	var candidates []candidate
+	for _, page := range newPages {
+		if old[page] != nil {
+			continue
+		}
+		lines := bodyLines(page)
+		shared := make(map[*Page]int)
+		for line := range lines {
+			for _, o := range withLine[line] {
+				shared[o]++
+			}
+		}
+		for o, n := range shared {
+			score := 2 * float64(n) / float64(len(lines)+size[o])
+			if score >= similarity {
+				candidates = append(candidates, candidate{o, page, score})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if a.n.index != b.n.index {
+			return a.n.index < b.n.index
+		}
+		return a.o.index < b.o.index
+	})
+	for _, c := range candidates {
+		if !matched[c.o] && old[c.n] == nil {
+			pair(c.o, c.n)
+		}
+	}
+}
+
+// inOrder returns the matched new pages that are in the same order as the
+// old pages they were matched to, the most there can be: a longest
+// increasing subsequence of the old positions, found by patience sorting.
+func inOrder(oldPages, newPages []*Page, old map[*Page]*Page) map[*Page]bool {
+	position := make(map[*Page]int)
+	for i, page := range oldPages {
+		position[page] = i
+	}
+	var pages []*Page
+	for _, page := range newPages {
+		if old[page] != nil {
+			pages = append(pages, page)
+		}
+	}
+
+	// tails[k] is the page that ends the increasing subsequence of length
+	// k+1 with the lowest old position, and prev links it to the one
+	// before it
+	var tails []int
+	prev := make([]int, len(pages))
+	for i, page := range pages {
+		at := position[old[page]]
+		k := sort.Search(len(tails), func(k int) bool {
+			return position[old[pages[tails[k]]]] >= at
+		})
+		prev[i] = -1
+		if k > 0 {
+			prev[i] = tails[k-1]
+		}
+		if k == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[k] = i
+		}
+	}
+
+	kept := make(map[*Page]bool)
+	if len(tails) > 0 {
+		for i := tails[len(tails)-1]; i >= 0; i = prev[i] {
+			kept[pages[i]] = true
+		}
+	}
+	return kept
+}
+
+// bodyLines returns the set of lines in page's body that aren't blank.
+func bodyLines(page *Page) map[string]bool {
+	lines := make(map[string]bool)
+	for _, line := range page.Lines() {
+		if len(bytes.TrimSpace(line)) > 0 {
+			lines[string(bytes.TrimRight(line, "\n"))] = true
+		}
+	}
+	return lines
+}
+
+// diff returns how o changed into n, or that n was added if o is nil.
+// Whether n was reordered depends on the other pages, so it is given.
+func diff(o, n *Page, reordered bool) PageDiff {
+	if o == nil {
+		return PageDiff{nil, n, Added}
+	}
+	var d Difference
+	if o.Address() != n.Address() {
+		d |= Moved
+	}
+	if reordered {
+		d |= Reordered
+	}
+	if o.titles[0] != n.titles[0] {
+		d |= Retitled
+	}
+	if !equalStrings(o.In(), n.In()) ||
+		!equalStrings(o.Alternates(), n.Alternates()) {
+		d |= Retagged
+	}
+	if !bytes.Equal(o.Body(), n.Body()) {
+		d |= BodyEdited
+	}
+	return PageDiff{o, n, d}
+}
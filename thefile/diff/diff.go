@@ -0,0 +1,224 @@
+// Command diff compares two versions of the file page by page, so pages that
+// only moved don't hide the ones that changed. Edited bodies are shown line by
+// line. The new version defaults to the file in storage.
+//
+// Usage:
+//
+//	diff [-all] old [new]
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"sethwklein.net/thefile/thefile"
+)
+
+// name returns the name of page and its address.
+func name(page *thefile.Page) string {
+	name, anonymous := page.Name()
+	if anonymous {
+		name = "(anonymous)"
+	}
+	return fmt.Sprintf("%s (line %d)", name, page.Address())
+}
+
+// lengths returns the lengths of the longest common subsequences of a and
+// each prefix of b, indexed by the length of the prefix.
+func lengths(a, b [][]byte) []int {
+	row := make([]int, len(b)+1)
+	for _, line := range a {
+		diagonal := 0
+		for j := 1; j <= len(b); j++ {
+			above := row[j]
+			switch {
+			case bytes.Equal(line, b[j-1]):
+				row[j] = diagonal + 1
+			case row[j-1] > row[j]:
+				row[j] = row[j-1]
+			}
+			diagonal = above
+		}
+	}
+	return row
+}
+
+// reversed returns the lines in reverse order.
+func reversed(lines [][]byte) [][]byte {
+	r := make([][]byte, len(lines))
+	for i, line := range lines {
+		r[len(lines)-1-i] = line
+	}
+	return r
+}
+
+// repeat appends n copies of op to ops.
+func repeat(ops []byte, op byte, n int) []byte {
+	for ; n > 0; n-- {
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+// align appends to ops the steps from a to b along a longest common
+// subsequence: '=' for a line in both, '-' for a line only in a, and '+'
+// for a line only in b. It takes space linear in the lengths of a and b, by
+// Hirschberg's algorithm, after trimming the lines they start and end with,
+// which are usually most of a body.
+func align(ops []byte, a, b [][]byte) []byte {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && bytes.Equal(a[prefix], b[prefix]) {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		bytes.Equal(a[len(a)-1-suffix], b[len(b)-1-suffix]) {
+		suffix++
+	}
+	ops = repeat(ops, '=', prefix)
+	a, b = a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	switch {
+	case len(a) < 1 || len(b) < 1:
+		ops = repeat(ops, '-', len(a))
+		ops = repeat(ops, '+', len(b))
+	case len(a) < 2:
+		j := 0
+		for j < len(b) && !bytes.Equal(a[0], b[j]) {
+			j++
+		}
+		if j == len(b) {
+			ops = append(ops, '-')
+			ops = repeat(ops, '+', len(b))
+			break
+		}
+		ops = repeat(ops, '+', j)
+		ops = append(ops, '=')
+		ops = repeat(ops, '+', len(b)-j-1)
+	default:
+		// split b where the halves of a share the most lines with it
+		mid := len(a) / 2
+		front := lengths(a[:mid], b)
+		back := lengths(reversed(a[mid:]), reversed(b))
+		split := 0
+		for j := range front {
+			if front[j]+back[len(b)-j] > front[split]+back[len(b)-split] {
+				split = j
+			}
+		}
+		ops = align(ops, a[:mid], b[:split])
+		ops = align(ops, a[mid:], b[split:])
+	}
+	return repeat(ops, '=', suffix)
+}
+
+// writeBody writes the lines removed from and added to the body, with the
+// number of the line in the new body where each run of changes starts.
+func writeBody(out io.Writer, o, n *thefile.Page) {
+	a, b := o.Lines(), n.Lines()
+	line := func(prefix byte, text []byte) {
+		fmt.Fprintf(out, "\t%c%s\n", prefix, bytes.TrimRight(text, "\n"))
+	}
+	inRun := false
+	i, j := 0, 0
+	for _, op := range align(nil, a, b) {
+		if op == '=' {
+			inRun = false
+			i++
+			j++
+			continue
+		}
+		if !inRun {
+			fmt.Fprintf(out, "\t@ body line %d\n", j+1)
+			inRun = true
+		}
+		if op == '-' {
+			line('-', a[i])
+			i++
+		} else {
+			line('+', b[j])
+			j++
+		}
+	}
+}
+
+func write(out io.Writer, d thefile.PageDiff) {
+	switch {
+	case d.Old == nil:
+		fmt.Fprintf(out, "%v: %s\n", d.Difference, name(d.New))
+		return
+	case d.New == nil:
+		fmt.Fprintf(out, "%v: %s\n", d.Difference, name(d.Old))
+		return
+	case d.Difference&(thefile.Moved|thefile.Retitled) != 0:
+		fmt.Fprintf(out, "%v: %s -> %s\n", d.Difference, name(d.Old),
+			name(d.New))
+	default:
+		fmt.Fprintf(out, "%v: %s\n", d.Difference, name(d.New))
+	}
+	if d.Difference&thefile.Retagged != 0 {
+		for _, titles := range []struct {
+			label    string
+			old, new []string
+		}{
+			{"titles", d.Old.In(), d.New.In()},
+			{"alternates", d.Old.Alternates(), d.New.Alternates()},
+		} {
+			if fmt.Sprintf("%q", titles.old) != fmt.Sprintf("%q", titles.new) {
+				fmt.Fprintf(out, "\t%s: %q -> %q\n", titles.label,
+					titles.old, titles.new)
+			}
+		}
+	}
+	if d.Difference&thefile.BodyEdited != 0 {
+		writeBody(out, d.Old, d.New)
+	}
+}
+
+func mainError() error {
+	all := flag.Bool("all", false, "also list pages that are unchanged")
+	flag.Parse()
+	if flag.NArg() < 1 || flag.NArg() > 2 {
+		return fmt.Errorf("expected one or two files, got %d", flag.NArg())
+	}
+
+	oldPages, err := thefile.PagesFrom(thefile.FileSource(flag.Arg(0)))
+	if err != nil {
+		return err
+	}
+	source := thefile.Storage
+	if flag.NArg() > 1 {
+		source = thefile.FileSource(flag.Arg(1))
+	}
+	newPages, err := thefile.PagesFrom(source)
+	if err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	for _, d := range thefile.Diff(oldPages, newPages) {
+		if d.Difference == 0 && !*all {
+			continue
+		}
+		write(out, d)
+	}
+	return out.Flush()
+}
+
+func mainCode() int {
+	err := mainError()
+	if err == nil {
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "%v: Error: %v\n", filepath.Base(os.Args[0]), err)
+	return 1
+}
+
+func main() {
+	os.Exit(mainCode())
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+// lcs returns the length of the longest common subsequence of a and b, the
+// slow way.
+func lcs(a, b [][]byte) int {
+	if len(a) < 1 || len(b) < 1 {
+		return 0
+	}
+	if bytes.Equal(a[0], b[0]) {
+		return lcs(a[1:], b[1:]) + 1
+	}
+	x, y := lcs(a[1:], b), lcs(a, b[1:])
+	if x > y {
+		return x
+	}
+	return y
+}
+
+func TestAlign(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	random := func() [][]byte {
+		lines := make([][]byte, r.Intn(9))
+		for i := range lines {
+			lines[i] = []byte{"abc"[r.Intn(3)], '\n'}
+		}
+		return lines
+	}
+	for n := 0; n < 2000; n++ {
+		a, b := random(), random()
+		ops := align(nil, a, b)
+
+		// the steps turn a into b
+		var i, j, same int
+		for _, op := range ops {
+			switch op {
+			case '=':
+				if i >= len(a) || j >= len(b) || !bytes.Equal(a[i], b[j]) {
+					t.Fatalf("%q -> %q: %s: bad =", a, b, ops)
+				}
+				i, j, same = i+1, j+1, same+1
+			case '-':
+				i++
+			case '+':
+				j++
+			}
+		}
+		if i != len(a) || j != len(b) {
+			t.Fatalf("%q -> %q: %s: not every line", a, b, ops)
+		}
+		if expected := lcs(a, b); same != expected {
+			t.Errorf("%q -> %q: %s:\nexpected: %d lines kept\nactual:   %d",
+				a, b, ops, expected, same)
+		}
+	}
+}
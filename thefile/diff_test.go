@@ -0,0 +1,132 @@
+package thefile
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	old := "----same\n\nunchanged\n\n" +
+		"----moves\n\nmoving\n\n" +
+		"----edited\n\nbefore\n\n" +
+		"----old name\n\nrenamed\n\n" +
+		"----tagged\n----a\n\ntags\n\n" +
+		"----renamed and edited\n\none\ntwo\nthree\nfour\n\n" +
+		"----gone\n\ndeleted\n"
+	new := "----same\n\nunchanged\n\n" +
+		"----added\n\nnew\n\n" +
+		"----edited\n\nafter\n\n" +
+		"----new name\n\nrenamed\n\n" +
+		"----tagged\n----b\n\ntags\n\n" +
+		"----different\n\none\ntwo\nthree\nfive\n\n" +
+		"----moves\n\nmoving\n"
+	oldPages, _, err := parsePages([]byte(old))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newPages, _, err := parsePages([]byte(new))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		old, new   string
+		difference Difference
+	}{
+		{"same", "same", 0},
+		{"", "added", Added},
+		{"edited", "edited", BodyEdited},
+		{"old name", "new name", Retitled},
+		{"tagged", "tagged", Retagged},
+		{"renamed and edited", "different", Retitled | BodyEdited},
+		{"moves", "moves", Moved | Reordered},
+		{"gone", "", Deleted},
+	}
+	diffs := Diff(oldPages, newPages)
+	if len(diffs) != len(tests) {
+		t.Fatalf("expected %d diffs, got %d", len(tests), len(diffs))
+	}
+	for i, test := range tests {
+		d := diffs[i]
+		var oldName, newName string
+		if d.Old != nil {
+			oldName, _ = d.Old.Name()
+		}
+		if d.New != nil {
+			newName, _ = d.New.Name()
+		}
+		if oldName != test.old || newName != test.new || d.Difference != test.difference {
+			t.Errorf("%d:\nexpected: %q %q %v\nactual:   %q %q %v", i,
+				test.old, test.new, test.difference,
+				oldName, newName, d.Difference)
+		}
+	}
+}
+
+func TestDiffReordered(t *testing.T) {
+	tests := []struct {
+		old, new         string
+		moved, reordered []string
+	}{
+		// pages after an added page move but aren't reordered
+		{"----one\n\n----two\n", "----zero\n\n----one\n\n----two\n",
+			[]string{"one", "two"}, nil},
+		// nor are pages after a deleted page
+		{"----one\n\n----two\n\n----three\n", "----two\n\n----three\n",
+			[]string{"two", "three"}, nil},
+		// or edited and renamed ones that kept their place
+		{"----one\n\na\nb\n\n----two\n", "----zero\n\n----uno\n\na\nb\n\n----two\n",
+			[]string{"uno", "two"}, nil},
+		// only the page that was moved is
+		{"----one\n\n----two\n\n----three\n\n----four\n",
+			"----four\n\n----one\n\n----two\n\n----three\n",
+			[]string{"four", "one", "two", "three"}, []string{"four"}},
+		{"----one\n\n----two\n\n----three\n", "----one\n\n----three\n\n----two\n",
+			[]string{"three", "two"}, []string{"three"}},
+	}
+	for _, test := range tests {
+		oldPages, _, err := parsePages([]byte(test.old))
+		if err != nil {
+			t.Fatal(err)
+		}
+		newPages, _, err := parsePages([]byte(test.new))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var moved, reordered []string
+		for _, d := range Diff(oldPages, newPages) {
+			if d.New == nil {
+				continue
+			}
+			name, _ := d.New.Name()
+			if d.Difference&Moved != 0 {
+				moved = append(moved, name)
+			}
+			if d.Difference&Reordered != 0 {
+				reordered = append(reordered, name)
+			}
+		}
+		if fmt.Sprint(moved, reordered) != fmt.Sprint(test.moved, test.reordered) {
+			t.Errorf("%q -> %q:\nexpected: %q %q\nactual:   %q %q", test.old, test.new,
+				test.moved, test.reordered, moved, reordered)
+		}
+	}
+}
+
+func TestDifferenceString(t *testing.T) {
+	tests := []struct {
+		difference Difference
+		expected   string
+	}{
+		{0, "unchanged"},
+		{Moved, "moved"},
+		{Retitled | BodyEdited, "retitled, body edited"},
+		{Deleted, "deleted"},
+		{Moved | Reordered, "moved, reordered"},
+	}
+	for _, test := range tests {
+		if actual := test.difference.String(); actual != test.expected {
+			t.Errorf("\nexpected: %q\nactual:   %q", test.expected, actual)
+		}
+	}
+}
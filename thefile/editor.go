@@ -0,0 +1,348 @@
+package thefile
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// EditError is returned by Editor when an edit can't be made, such as when a
+// new body would be read as more than one page. The Editor is unchanged.
+type EditError struct {
+	Op    string
+	Index int
+	Msg   string
+}
+
+// Error satisfies the error interface.
+func (err EditError) Error() string {
+	return fmt.Sprintf("%s page %d: %s", err.Op, err.Index, err.Msg)
+}
+
+// Editor changes pages by replacing only the bytes of the file they occupy,
+// so every other page stays byte for byte the same. After each change,
+// Buffer and Pages return the new file and its pages, the same as Pages
+// would find. Pages are referred to by index in Pages.
+type Editor struct {
+	buf   []byte
+	pages []*Page
+	o     *options
+	crlf  bool
+}
+
+// NewEditor returns an Editor for buf, which is not modified. Like Pages,
+// it removes any byte order mark and replaces CRLF line endings with LF,
+// unless given WithOriginalLineEndings, when buf is kept as it is. The lines
+// the Editor writes, including those of new bodies, end in LF, or in CRLF
+// if buf is kept and its first line does. Options are as for Pages, except
+// that buf is read instead of a Source.
+func NewEditor(buf []byte, opts ...Option) (*Editor, error) {
+	o := newOptions(opts...)
+	buf = normalize(buf, o.keepCRLF, &Statistics{})
+	pages, _, err := o.parse(buf)
+	if err != nil {
+		return nil, err
+	}
+	end := bytes.IndexByte(buf, '\n')
+	crlf := o.keepCRLF && end > 0 && buf[end-1] == '\r'
+	return &Editor{buf: buf, pages: pages, o: o, crlf: crlf}, nil
+}
+
+// Buffer returns the file as edited.
+func (ed *Editor) Buffer() []byte {
+	return ed.buf
+}
+
+// Pages returns the pages in Buffer.
+func (ed *Editor) Pages() []*Page {
+	return ed.pages
+}
+
+// newlines returns text with the line endings of the file.
+func (ed *Editor) newlines(text []byte) []byte {
+	text = bytes.ReplaceAll(text, crlf, []byte{'\n'})
+	if !ed.crlf {
+		return text
+	}
+	return bytes.ReplaceAll(text, []byte{'\n'}, crlf)
+}
+
+// expected describes the page an edit should produce.
+type expected struct {
+	titles []string
+	body   []byte
+}
+
+// splice replaces ed.buf[low:high] with text, and checks that the result
+// differs by only the page with index i: changed to want if delta is 0,
+// added as want if delta is 1, or removed if delta is -1.
+func (ed *Editor) splice(op string, i, low, high int, text []byte, delta int, want expected) error {
+	fail := func(format string, args ...interface{}) error {
+		return EditError{op, i, fmt.Sprintf(format, args...)}
+	}
+	update, err := ed.o.reparse(ed.pages, ed.buf, low, high, text)
+	if err != nil {
+		return fail("%v", err)
+	}
+	if len(update.Pages) != len(ed.pages)+delta {
+		return fail("the file would have %d pages instead of %d",
+			len(update.Pages), len(ed.pages)+delta)
+	}
+	switch delta {
+	case 0:
+		if len(update.Added)+len(update.Removed) > 0 ||
+			len(update.Changed) > 1 ||
+			(len(update.Changed) == 1 && update.Changed[0] != i) {
+			return fail("other pages would change")
+		}
+	case 1:
+		if len(update.Added) != 1 || len(update.Removed)+len(update.Changed) > 0 {
+			return fail("other pages would change")
+		}
+	case -1:
+		if len(update.Removed) != 1 || len(update.Added)+len(update.Changed) > 0 {
+			return fail("other pages would change")
+		}
+	}
+	if delta >= 0 {
+		page := update.Pages[i]
+		if titles := cleanTitles(want.titles); !equalStrings(page.titles, titles) {
+			return fail("titles would be %q instead of %q", page.titles, titles)
+		}
+		if !bytes.Equal(page.Body(), want.body) {
+			return fail("body would be %q instead of %q", page.Body(), want.body)
+		}
+	}
+	ed.buf, ed.pages = update.Buffer, update.Pages
+	return nil
+}
+
+// cleanTitles returns titles as makeTitles would find them on title lines.
+func cleanTitles(titles []string) []string {
+	var clean []string
+line:
+	for i, title := range titles {
+		if len(title) < 1 && i > 0 {
+			continue
+		}
+		for _, existing := range clean {
+			if title == existing {
+				continue line
+			}
+		}
+		clean = append(clean, title)
+	}
+	if clean == nil {
+		return []string{""}
+	}
+	return clean
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// titleLines returns the title lines for titles.
+func (ed *Editor) titleLines(titles []string) ([]byte, error) {
+	if len(titles) < 1 {
+		return nil, fmt.Errorf("no titles")
+	}
+	var lines []byte
+	for _, title := range titles {
+		line, ok := ed.o.titleLine(title, false)
+		if !ok {
+			return nil, fmt.Errorf("title %q would not be read back the same",
+				title)
+		}
+		lines = append(lines, line...)
+	}
+	return ed.newlines(lines), nil
+}
+
+// page returns the page with index i, or an error for op if there is none.
+func (ed *Editor) page(op string, i int) (*Page, error) {
+	if i < 0 || i >= len(ed.pages) {
+		return nil, EditError{op, i, "no such page"}
+	}
+	return ed.pages[i], nil
+}
+
+// end returns the offset just past page's body.
+func (page *Page) end() int {
+	return page.all[len(page.all)-1]
+}
+
+// SetTitles replaces the title lines of the page with index i with lines for
+// titles. If the page had no title lines, they are added before its body.
+func (ed *Editor) SetTitles(i int, titles []string) error {
+	const op = "set titles of"
+	page, err := ed.page(op, i)
+	if err != nil {
+		return err
+	}
+	lines, err := ed.titleLines(titles)
+	if err != nil {
+		return EditError{op, i, err.Error()}
+	}
+	want := expected{titles, page.Body()}
+	if page.head < 1 {
+		// the clear that separates the new head from the body
+		lines = append(lines, ed.newlines([]byte{'\n'})...)
+	}
+	start := page.all[0]
+	return ed.splice(op, i, start, page.all[page.head], lines, 0, want)
+}
+
+// AddTag adds a title line for tag to the end of the titles of the page with
+// index i, unless it already has that title.
+func (ed *Editor) AddTag(i int, tag string) error {
+	const op = "add tag to"
+	page, err := ed.page(op, i)
+	if err != nil {
+		return err
+	}
+	for _, title := range page.titles {
+		if title == tag {
+			return nil
+		}
+	}
+	titles := append(page.titles[:len(page.titles):len(page.titles)], tag)
+	if page.head < 1 {
+		return ed.SetTitles(i, titles)
+	}
+	line, err := ed.titleLines([]string{tag})
+	if err != nil {
+		return EditError{op, i, err.Error()}
+	}
+	at := page.all[page.head]
+	return ed.splice(op, i, at, at, line, 0, expected{titles, page.Body()})
+}
+
+// RemoveTag removes the title lines for tag from the page with index i. The
+// other title lines are left as they are. It is an error to remove the last
+// title.
+func (ed *Editor) RemoveTag(i int, tag string) error {
+	const op = "remove tag from"
+	page, err := ed.page(op, i)
+	if err != nil {
+		return err
+	}
+	tok, _ := ed.o.lines()
+	var kept []byte
+	var titles []string
+	found := false
+	for j := 0; j < page.head; j++ {
+		line := ed.buf[page.all[j]:page.all[j+1]]
+		token, _ := tok.Line(line)
+		title := string(ed.o.title(line, token, 0))
+		if title == tag {
+			found = true
+			continue
+		}
+		kept = append(kept, line...)
+		titles = append(titles, title)
+	}
+	if !found {
+		return nil
+	}
+	if len(kept) < 1 {
+		return EditError{op, i, "a page must keep at least one title"}
+	}
+	return ed.splice(op, i, page.all[0], page.all[page.head], kept, 0,
+		expected{titles, page.Body()})
+}
+
+// ReplaceBody replaces the body of the page with index i.
+func (ed *Editor) ReplaceBody(i int, body []byte) error {
+	const op = "replace body of"
+	page, err := ed.page(op, i)
+	if err != nil {
+		return err
+	}
+	body = ed.newlines(body)
+	want := expected{page.titles, body}
+	if page.head < 1 {
+		want.titles = nil
+	}
+	bodyLow := page.offsets[0]
+	headEnd := page.all[page.head]
+	switch {
+	case len(body) > 0 && page.end() > headEnd:
+		return ed.splice(op, i, bodyLow, page.end(), body, 0, want)
+	case len(body) > 0:
+		// add the clear between the head and the body
+		text := append(ed.newlines([]byte{'\n'}), body...)
+		return ed.splice(op, i, headEnd, headEnd, text, 0, want)
+	case page.head < 1:
+		return EditError{op, i, "a page with no titles must have a body"}
+	}
+	// remove the clear between the head and the body too
+	return ed.splice(op, i, headEnd, page.end(), nil, 0, want)
+}
+
+// InsertPageAfter adds a page with titles and body after the page with index
+// i, or at the start of the file if i is -1.
+func (ed *Editor) InsertPageAfter(i int, titles []string, body []byte) error {
+	const op = "insert after"
+	if i < -1 || i >= len(ed.pages) {
+		return EditError{op, i, "no such page"}
+	}
+	text, err := ed.titleLines(titles)
+	if err != nil {
+		return EditError{op, i, err.Error()}
+	}
+	body = ed.newlines(body)
+	clear := ed.newlines([]byte{'\n'})
+	if len(body) > 0 {
+		text = append(text, clear...)
+		text = append(text, body...)
+	}
+	want := expected{titles, body}
+
+	var at int
+	switch {
+	case i+1 < len(ed.pages):
+		// before the next page, which already has a clear before it
+		at = ed.pages[i+1].all[0]
+		text = append(text, clear...)
+	case i >= 0:
+		// after the last page, which has no clear after it
+		at = ed.pages[i].end()
+		text = append(clear, text...)
+	default:
+		// no pages, but maybe clears, which are not before a page
+		at = len(ed.buf)
+		if at > 0 && ed.buf[at-1] != '\n' {
+			return EditError{op, i, "the file doesn't end in a newline"}
+		}
+	}
+	if err := ed.splice(op, i+1, at, at, text, 1, want); err != nil {
+		return EditError{op, i, err.(EditError).Msg}
+	}
+	return nil
+}
+
+// DeletePage removes the page with index i and the clear that separated it
+// from the page before or after it.
+func (ed *Editor) DeletePage(i int) error {
+	const op = "delete"
+	page, err := ed.page(op, i)
+	if err != nil {
+		return err
+	}
+	low, high := page.all[0], page.end()
+	switch {
+	case i+1 < len(ed.pages):
+		high = ed.pages[i+1].all[0]
+	case i > 0:
+		low = ed.pages[i-1].end()
+	}
+	return ed.splice(op, i, low, high, nil, -1, expected{})
+}
@@ -0,0 +1,133 @@
+package thefile
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestEditor(t *testing.T) {
+	ed, err := NewEditor([]byte("----one\n\nbody\n\n----two\n----tag\n\nmore\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps := []struct {
+		edit     func() error
+		expected string
+	}{
+		{func() error { return ed.AddTag(0, "new") },
+			"----one\n----new\n\nbody\n\n----two\n----tag\n\nmore\n"},
+		{func() error { return ed.RemoveTag(1, "two") },
+			"----one\n----new\n\nbody\n\n----tag\n\nmore\n"},
+		{func() error { return ed.SetTitles(0, []string{"first"}) },
+			"----first\n\nbody\n\n----tag\n\nmore\n"},
+		{func() error { return ed.ReplaceBody(1, []byte("changed\n")) },
+			"----first\n\nbody\n\n----tag\n\nchanged\n"},
+		{func() error { return ed.InsertPageAfter(0, []string{"middle"}, nil) },
+			"----first\n\nbody\n\n----middle\n\n----tag\n\nchanged\n"},
+		{func() error { return ed.InsertPageAfter(2, []string{"last"}, []byte("end\n")) },
+			"----first\n\nbody\n\n----middle\n\n----tag\n\nchanged\n\n----last\n\nend\n"},
+		{func() error { return ed.ReplaceBody(0, nil) },
+			"----first\n\n----middle\n\n----tag\n\nchanged\n\n----last\n\nend\n"},
+		{func() error { return ed.DeletePage(1) },
+			"----first\n\n----tag\n\nchanged\n\n----last\n\nend\n"},
+		{func() error { return ed.DeletePage(2) },
+			"----first\n\n----tag\n\nchanged\n"},
+	}
+	for n, step := range steps {
+		if err := step.edit(); err != nil {
+			t.Fatalf("step %d: %v", n, err)
+		}
+		if actual := string(ed.Buffer()); actual != step.expected {
+			t.Fatalf("step %d:\nexpected: %q\nactual:   %q", n, step.expected, actual)
+		}
+	}
+
+	// a body that would be read as a new page
+	err = ed.ReplaceBody(0, []byte("text\n\n----oops\n"))
+	if e, ok := err.(EditError); !ok || e.Index != 0 {
+		t.Errorf("expected EditError, got %v", err)
+	}
+	if actual := string(ed.Buffer()); actual != steps[len(steps)-1].expected {
+		t.Errorf("buffer changed by failed edit: %q", actual)
+	}
+}
+
+func TestEditorRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	bodies := []string{"", "text\n", "\n\ntext\n", "----x\ntext\n", "text\n\n",
+		"text\n\n----x\n", "no newline", "a\r\nb\r\n"}
+	titles := [][]string{{"one"}, {"", "two"}, {"=alt", "one"}, {"bad\n"}, nil}
+	for n := 0; n < 2000; n++ {
+		buf := randomFile(r, r.Intn(20))
+		var opts []Option
+		if n%2 == 1 {
+			buf = bytes.ReplaceAll(buf, []byte{'\n'}, []byte("\r\n"))
+			opts = append(opts, WithOriginalLineEndings())
+		}
+		// CRLF files are edited in place with CRLF line endings
+		crlf := bytes.Contains(buf, []byte{'\r'})
+		ed, err := NewEditor(buf, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if crlf && !bytes.Equal(buf, ed.Buffer()) {
+			t.Fatalf("\nbuf:    %q\nbuffer: %q", buf, ed.Buffer())
+		}
+		for k := 0; k < 5; k++ {
+			before := ed.Pages()
+			i := r.Intn(len(before) + 1)
+			body := []byte(bodies[r.Intn(len(bodies))])
+			title := titles[r.Intn(len(titles))]
+			var err error
+			switch r.Intn(6) {
+			case 0:
+				err = ed.SetTitles(i, title)
+			case 1:
+				err = ed.AddTag(i, "tag")
+			case 2:
+				err = ed.RemoveTag(i, "one")
+			case 3:
+				err = ed.ReplaceBody(i, body)
+			case 4:
+				err = ed.InsertPageAfter(i-1, title, body)
+			case 5:
+				err = ed.DeletePage(i)
+			}
+			expected, _, parseErr := newOptions(opts...).parse(ed.Buffer())
+			if parseErr != nil {
+				t.Fatal(parseErr)
+			}
+			if !samePages(t, expected, ed.Pages()) {
+				t.Fatalf("buffer: %q", ed.Buffer())
+			}
+			lf := bytes.Count(ed.Buffer(), []byte{'\n'})
+			if crlf && bytes.Count(ed.Buffer(), []byte("\r\n")) != lf {
+				t.Fatalf("LF line ending in CRLF buffer: %q", ed.Buffer())
+			}
+			if !crlf && bytes.Contains(ed.Buffer(), []byte{'\r'}) {
+				t.Fatalf("CR in LF buffer: %q", ed.Buffer())
+			}
+			if err != nil {
+				if _, ok := err.(EditError); !ok {
+					t.Fatalf("unexpected error type: %v", err)
+				}
+				continue
+			}
+			// the pages that weren't edited are the same bytes
+			after := ed.Pages()
+			same := 0
+			for _, page := range after {
+				for _, old := range before {
+					if bytes.Equal(page.All(), old.All()) {
+						same++
+						break
+					}
+				}
+			}
+			if same < len(before)-1 {
+				t.Fatalf("only %d of %d pages kept", same, len(before))
+			}
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package thefile
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"sethwklein.net/thefile/tokenizer"
+)
+
+// EncodeError is returned by Encoder when a page can't be written so that
+// reading it back gives the same titles and body.
+type EncodeError struct {
+	// Index is the number of pages written before the page.
+	Index int
+	Msg   string
+}
+
+// Error satisfies the error interface.
+func (err EncodeError) Error() string {
+	return fmt.Sprintf("page %d: %s", err.Index, err.Msg)
+}
+
+// Encoder writes pages in the format of the file: the title lines, a clear
+// line, and the body, with a clear line between pages. Reading the output
+// with Pages, with the same options, gives pages with the same titles,
+// bodies, and hashes, or Encoder returns an EncodeError. Alternate titles are
+// kept only if the dialect has an alternate marker or they are written
+// with their marker in the title, as DefaultDialect reads them.
+type Encoder struct {
+	w io.Writer
+	o *options
+
+	// n is the number of pages written.
+	n int
+	// unterminated is whether the last body didn't end in a newline.
+	unterminated bool
+	// clear is whether the last body ended in a clear line.
+	clear bool
+
+	err error
+}
+
+// NewEncoder returns an Encoder writing to w. Options other than those for
+// the dialect and tokenizer are ignored.
+func NewEncoder(w io.Writer, opts ...Option) *Encoder {
+	o := newOptions(opts...)
+	return &Encoder{w: w, o: o, err: o.err}
+}
+
+// machineTokens returns the tokens the machine reads for the lines in buf.
+func (e *Encoder) machineTokens(buf []byte) []tokenizer.Token {
+	tok, end := e.o.lines()
+	tokens, _ := tokenize(buf, tok, end, 0)
+	return e.o.alphabet().Map(tokens)
+}
+
+// bodyError returns why body can't be written after a clear line and before
+// a clear line or the end of the file, or "" if it can.
+func (e *Encoder) bodyError(body []byte) string {
+	tokens := e.machineTokens(body)
+	tokens = tokens[:len(tokens)-1]
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i] != 't' || (i > 0 && tokens[i-1] != 'c') {
+			continue
+		}
+		j := i
+		for j < len(tokens) && tokens[j] == 't' {
+			j++
+		}
+		if j == len(tokens) || tokens[j] == 'c' {
+			return fmt.Sprintf("body line %d would be read as titles", i+1)
+		}
+		i = j
+	}
+	return ""
+}
+
+// Encode writes page. Only the first page may have no title lines.
+func (e *Encoder) Encode(page *Page) error {
+	anonymous := page.head < 1
+	alternates := make(map[string]bool)
+	for _, title := range page.alternates {
+		alternates[title] = true
+	}
+	return e.encode(page.titles, alternates, page.Body(), anonymous)
+}
+
+func (e *Encoder) encode(titles []string, alternates map[string]bool, body []byte, anonymous bool) error {
+	if e.err != nil {
+		return e.err
+	}
+	index := e.n
+	fail := func(format string, args ...interface{}) error {
+		return EncodeError{index, fmt.Sprintf(format, args...)}
+	}
+	if e.unterminated {
+		return fail("the body before it doesn't end in a newline")
+	}
+
+	buf := &bytes.Buffer{}
+	if index > 0 {
+		buf.WriteByte('\n')
+	}
+	if anonymous {
+		if index > 0 {
+			return fail("only the first page may have no titles")
+		}
+		tokens := e.machineTokens(body)
+		i := 0
+		for tokens[i] == 'c' {
+			i++
+		}
+		switch tokens[i] {
+		case 'e':
+			return fail("a page with no titles must have a body that isn't all clear")
+		case 't':
+			if i > 0 {
+				return fail("a page with no titles can't start with clears and then titles")
+			}
+		}
+		if i > 0 {
+			// the first line is dropped if it's clear
+			buf.WriteByte('\n')
+		}
+	} else {
+		for _, title := range titles {
+			line, ok := e.o.titleLine(title, alternates[title])
+			if !ok {
+				return fail("title %q would not be read back the same", title)
+			}
+			buf.Write(line)
+		}
+		if len(body) > 0 {
+			buf.WriteByte('\n')
+		}
+	}
+	if msg := e.bodyError(body); msg != "" {
+		return fail("%s", msg)
+	}
+	buf.Write(body)
+
+	if _, e.err = e.w.Write(buf.Bytes()); e.err != nil {
+		return e.err
+	}
+	e.n++
+	e.unterminated = len(body) > 0 && body[len(body)-1] != '\n'
+	e.clear = false
+	if len(body) > 0 {
+		tokens := e.machineTokens(body)
+		e.clear = tokens[len(tokens)-2] == 'c'
+	}
+	return nil
+}
+
+// Close finishes the file. It doesn't close the underlying writer.
+func (e *Encoder) Close() error {
+	if e.err != nil {
+		return e.err
+	}
+	if e.clear {
+		// a clear at the end of the file is not part of the last body
+		_, e.err = e.w.Write([]byte{'\n'})
+	}
+	return e.err
+}
+
+// Marshal returns pages in the format of the file, as written by Encoder.
+func Marshal(pages []*Page, opts ...Option) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	e := NewEncoder(buf, opts...)
+	for _, page := range pages {
+		if err := e.Encode(page); err != nil {
+			return nil, err
+		}
+	}
+	if err := e.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,101 @@
+package thefile
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// sameContent reports whether expected and actual have the same titles,
+// alternates, bodies, and hashes.
+func sameContent(t *testing.T, expected, actual []*Page) bool {
+	if len(expected) != len(actual) {
+		t.Errorf("expected %d pages, got %d", len(expected), len(actual))
+		return false
+	}
+	for i := range expected {
+		e, a := expected[i], actual[i]
+		if !reflect.DeepEqual(e.titles, a.titles) ||
+			!reflect.DeepEqual(e.alternates, a.alternates) ||
+			!bytes.Equal(e.Body(), a.Body()) ||
+			!bytes.Equal(e.HashRaw(), a.HashRaw()) {
+			t.Errorf("page %d differs:\nexpected: %q %q\nactual:   %q %q", i,
+				e.titles, e.Body(), a.titles, a.Body())
+			return false
+		}
+	}
+	return true
+}
+
+func TestMarshal(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n < 2000; n++ {
+		buf := randomFile(r, r.Intn(30))
+		pages, _, err := parsePages(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := Marshal(pages)
+		if err != nil {
+			t.Fatalf("\nfile: %q\nerror: %v", buf, err)
+		}
+		again, _, err := parsePages(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !sameContent(t, pages, again) {
+			t.Fatalf("\nfile:   %q\noutput: %q", buf, out)
+		}
+	}
+}
+
+func TestEncoderErrors(t *testing.T) {
+	page := func(titles []string, body string) *Page {
+		buf := []byte(body)
+		p := &Page{titles: titles, file: buf, offsets: []int{0, len(buf)}}
+		if len(titles) > 0 && titles[0] != "" {
+			p.head = len(titles)
+		}
+		return p
+	}
+	tests := []struct {
+		pages []*Page
+		index int
+	}{
+		{[]*Page{page([]string{"one"}, "text\n\n----two\n")}, 0},
+		{[]*Page{page([]string{"one"}, "----two\n")}, 0},
+		{[]*Page{page([]string{"one "}, "text\n")}, 0},
+		{[]*Page{page([]string{"one"}, "no newline"), page([]string{"two"}, "")}, 1},
+		{[]*Page{page([]string{"one"}, "text\n"), page([]string{""}, "text\n")}, 1},
+		{[]*Page{page([]string{""}, "\n\n")}, 0},
+		{[]*Page{page([]string{""}, "\n----one\nother\n")}, 0},
+	}
+	for _, test := range tests {
+		_, err := Marshal(test.pages)
+		if e, ok := err.(EncodeError); !ok || e.Index != test.index {
+			t.Errorf("\ninput:    %q\nexpected: error for page %d\nactual:   %v",
+				test.pages[test.index].Body(), test.index, err)
+		}
+	}
+
+	// tricky but possible
+	pages := []*Page{
+		page([]string{""}, "\nother\n----one\nother\n"),
+		page([]string{"two", "=alt"}, "----three\nother\n\n"),
+		page([]string{"four"}, ""),
+		page([]string{"five"}, "\n\n"),
+	}
+	pages[1].alternates = []string{"=alt"}
+	out, err := Marshal(pages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _, err := parsePages(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameContent(t, pages, again) {
+		t.Errorf("output: %q", out)
+	}
+}
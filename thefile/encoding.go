@@ -0,0 +1,82 @@
+package thefile
+
+import (
+	"bytes"
+	"unicode/utf8"
+
+	"sethwklein.net/thefile/tokenizer"
+)
+
+// Copies of the file that pass through Windows editors come back with CRLF
+// line endings and sometimes a byte order mark. Neither should change titles
+// or hashes.
+
+// bom is the UTF-8 encoding of U+FEFF, the byte order mark.
+var bom = []byte{0xef, 0xbb, 0xbf}
+
+var crlf = []byte("\r\n")
+
+// normalize returns buf without a byte order mark and with CRLF line endings
+// replaced by LF, unless keepCRLF, when buf is returned as it is, recording
+// what it finds in stats. Buf is not modified. Tokenizing with
+// options.tokenize skips a byte order mark that is kept.
+func normalize(buf []byte, keepCRLF bool, stats *Statistics) []byte {
+	if bytes.HasPrefix(buf, bom) {
+		stats.BOM = true
+		if !keepCRLF {
+			buf = buf[len(bom):]
+		}
+	}
+
+	if !utf8.Valid(buf) {
+		for line, rest := 1, bytes.TrimPrefix(buf, bom); len(rest) > 0; line++ {
+			end := bytes.IndexByte(rest, '\n') + 1
+			if end < 1 {
+				end = len(rest)
+			}
+			if !utf8.Valid(rest[:end]) {
+				stats.InvalidUTF8 = append(stats.InvalidUTF8, line)
+			}
+			rest = rest[end:]
+		}
+	}
+
+	stats.CRLFLines = bytes.Count(buf, crlf)
+	if keepCRLF || stats.CRLFLines < 1 {
+		return buf
+	}
+	return bytes.ReplaceAll(buf, crlf, []byte{'\n'})
+}
+
+// crlfTokenizer tokenizes lines ending in CRLF as if they ended in LF, for
+// tokenizers that only expect LF.
+type crlfTokenizer struct {
+	tok  lineTokenizer
+	line []byte
+}
+
+// Line satisfies the lineTokenizer interface.
+func (t *crlfTokenizer) Line(buf []byte) (tokenizer.Token, int) {
+	n := bytes.IndexByte(buf, '\n') + 1
+	if n < 2 || buf[n-2] != '\r' {
+		return t.tok.Line(buf)
+	}
+	t.line = append(append(t.line[:0], buf[:n-2]...), '\n')
+	token, _ := t.tok.Line(t.line)
+	return token, n
+}
+
+// tokenize returns the tokens and offsets for the lines in buf, as tokenize
+// does, but skipping a byte order mark at the start of buf, which isn't part
+// of the first line.
+func (o *options) tokenize(buf []byte) (tokens []tokenizer.Token, offsets []int) {
+	tok, end := o.lines()
+	if !bytes.HasPrefix(buf, bom) {
+		return tokenize(buf, tok, end, o.capacity)
+	}
+	tokens, offsets = tokenize(buf[len(bom):], tok, end, o.capacity)
+	for i := range offsets {
+		offsets[i] += len(bom)
+	}
+	return tokens, offsets
+}
@@ -0,0 +1,61 @@
+package thefile
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestLineEndings(t *testing.T) {
+	lf := []byte("----one\n----two \n\nbody\nmore\n\n----three\n\nlast")
+	crlf := append([]byte("\xef\xbb\xbf"), bytes.ReplaceAll(lf, []byte("\n"), []byte("\r\n"))...)
+
+	expected, _, err := PagesStatisticsFrom(BytesSource(lf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, stats, err := PagesStatisticsFrom(BytesSource(crlf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stats.BOM || stats.CRLFLines != 8 || stats.InvalidUTF8 != nil {
+		t.Errorf("wrong statistics: %+v", stats)
+	}
+	if len(expected) != len(actual) {
+		t.Fatalf("\nexpected: %v\nactual:   %v", expected, actual)
+	}
+	for i := range expected {
+		if !reflect.DeepEqual(expected[i].Tags(), actual[i].Tags()) ||
+			!bytes.Equal(expected[i].HashRaw(), actual[i].HashRaw()) ||
+			expected[i].Address() != actual[i].Address() {
+			t.Errorf("\nexpected: %q %q\nactual:   %q %q", expected[i].Tags(),
+				expected[i].Body(), actual[i].Tags(), actual[i].Body())
+		}
+	}
+
+	kept, err := PagesFrom(BytesSource(crlf), WithOriginalLineEndings())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kept) != len(expected) {
+		t.Fatalf("\nexpected: %v\nactual:   %v", expected, kept)
+	}
+	for i := range expected {
+		if !reflect.DeepEqual(expected[i].Tags(), kept[i].Tags()) {
+			t.Errorf("\nexpected: %q\nactual:   %q", expected[i].Tags(), kept[i].Tags())
+		}
+	}
+	if body := kept[0].Body(); !bytes.Equal(body, []byte("body\r\nmore\r\n")) {
+		t.Errorf("line endings not kept: %q", body)
+	}
+}
+
+func TestInvalidUTF8(t *testing.T) {
+	_, stats, err := PagesStatisticsFrom(BytesSource([]byte("----ok\n\nbad \xff\nok\n\xc3\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []int{3, 5}; !reflect.DeepEqual(expected, stats.InvalidUTF8) {
+		t.Errorf("\nexpected: %v\nactual:   %v", expected, stats.InvalidUTF8)
+	}
+}
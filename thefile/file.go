@@ -2,13 +2,11 @@
 package thefile
 
 import (
-	"bytes"
 	"crypto/sha256"
 	"encoding/base64"
-	"unicode"
+	"runtime"
 
 	"sethwklein.net/thefile/parser"
-	"sethwklein.net/thefile/storage"
 	"sethwklein.net/thefile/tokenizer"
 )
 
@@ -19,6 +17,10 @@ type Page struct {
 	// It always contains at least one title.
 	titles []string
 
+	// alternates contains the titles from alternate title lines, with
+	// duplicates and zero length titles removed. They are also in titles.
+	alternates []string
+
 	// file is the original file contents, used for creating body slices.
 	file []byte
 
@@ -34,8 +36,17 @@ type Page struct {
 	// line is the number of first line of the head. Used for Address.
 	line int
 
-	// index is the page index. Used for Index.
+	// head is the number of lines in the head. Used for Reparse.
+	head int
+
+	// index is the page index. Used for Index. For children, it is the
+	// index among the children of the parent.
 	index int
+
+	// parent, children, and depth are set only by NestedPages.
+	parent   *Page
+	children []*Page
+	depth    int
 }
 
 // I frequently want the first title in string form. I must not forget to
@@ -104,6 +115,15 @@ func (page *Page) In() []string {
 	return page.titles[1:]
 }
 
+// I mark aliases, and titles that should be unique, with alternate title
+// lines.
+
+// Alternates returns the titles from alternate title lines, which are also
+// returned by Tags. Duplicated titles appear only the first time.
+func (page *Page) Alternates() []string {
+	return page.alternates
+}
+
 // Some parsers want the entire body.
 
 // Body returns the body all at once.
@@ -196,16 +216,9 @@ func (page *Page) Hash64() string {
 	return base64.RawURLEncoding.EncodeToString(page.HashRaw())
 }
 
-// parseTitle returns "title" from "----title\n", trimming trailing whitespace.
-func parseTitle(line []byte) []byte {
-	if len(line) <= 5 {
-		return nil
-	}
-	return bytes.TrimRightFunc(line[4:len(line)-1], unicode.IsSpace)
-}
-
-// makeTitles returns what should go in Page.titles.
-func makeTitles(buf []byte, offsets []int, head parser.Part) []string {
+// makeTitles returns what should go in Page.titles. Title returns the title
+// of the line with the given index.
+func makeTitles(head parser.Part, title func(int) string) []string {
 	length := head.High - head.Low
 	// the file can start with body content, creating a page with no header
 	if length < 1 {
@@ -216,7 +229,7 @@ func makeTitles(buf []byte, offsets []int, head parser.Part) []string {
 
 line:
 	for i := head.Low; i < head.High; i++ {
-		title := string(parseTitle(buf[offsets[i]:offsets[i+1]]))
+		title := title(i)
 		if len(title) < 1 && i > head.Low {
 			continue
 		}
@@ -231,7 +244,71 @@ line:
 	return titles
 }
 
-func pagesFrom(buf []byte) (pages []*Page, nLines int) {
+// makeAlternates returns what should go in Page.alternates. Alternate is the
+// token for alternate titles, or 0 for none. Title is as for makeTitles.
+func makeAlternates(tokens []tokenizer.Token, head parser.Part, alternate tokenizer.Token, title func(int) string) []string {
+	var alternates []string
+	if alternate == 0 {
+		return nil
+	}
+
+line:
+	for i := head.Low; i < head.High; i++ {
+		if tokens[i] != alternate {
+			continue
+		}
+		title := title(i)
+		if len(title) < 1 {
+			continue
+		}
+		for _, existing := range alternates {
+			if title == existing {
+				continue line
+			}
+		}
+		alternates = append(alternates, title)
+	}
+
+	return alternates
+}
+
+// set fills in page from p, the parser's page with index i. Offsets and
+// tokens are for the lines in buf, and tokens are from o's tokenizer.
+// Page.depth must already be set.
+func (page *Page) set(o *options, buf []byte, offsets []int, tokens []tokenizer.Token, p parser.Page, i int) {
+	title := func(i int) string {
+		return string(o.title(buf[offsets[i]:offsets[i+1]], tokens[i],
+			page.depth))
+	}
+	page.titles = makeTitles(p.Head, title)
+	page.alternates = makeAlternates(tokens, p.Head, o.alternate(), title)
+	page.file = buf
+	page.offsets = offsets[p.Body.Low : p.Body.High+1]
+	page.all = offsets[p.Head.Low : p.Body.High+1]
+	page.line = p.Head.Low + 1
+	page.head = p.Head.High - p.Head.Low
+	page.index = i
+}
+
+// Tokenize returns the tokens for the lines in buf, from tokenizer.Default,
+// and the offset of the start of each line. The last token is the end of file
+// token, with an offset of len(buf). Use parser.DefaultAlphabet to parse them.
+// The tokens are those Pages finds: a byte order mark is skipped, so the
+// first offset is after it, and lines ending in CRLF are read as if they
+// ended in LF.
+func Tokenize(buf []byte) (tokens []tokenizer.Token, offsets []int) {
+	return newOptions(WithOriginalLineEndings()).tokenize(buf)
+}
+
+// tokenize is Tokenize using tok, which returns end at the end of the file,
+// allocating room for capacity lines, or an estimate if capacity is 0.
+func tokenize(buf []byte, tok lineTokenizer, end tokenizer.Token, capacity int) (tokens []tokenizer.Token, offsets []int) {
+	if capacity > 0 {
+		tokens = make([]tokenizer.Token, 0, capacity)
+		offsets = make([]int, 0, capacity)
+		return tokenizeInto(buf, tok, end, tokens, offsets)
+	}
+
 	// magic constants determined by looking at output of average/average.go.
 	// lowering length provides no gains distinguishable from the noise.
 	skip := 0
@@ -259,54 +336,79 @@ func pagesFrom(buf []byte) (pages []*Page, nLines int) {
 		estimate = len(buf) / (average - fudge)
 	}
 
-	tokens := make([]tokenizer.Token, 0, estimate)
-	offsets := make([]int, 0, estimate)
-	tok := tokenizer.Default
-	tok.A = 't'
+	tokens = make([]tokenizer.Token, 0, estimate)
+	offsets = make([]int, 0, estimate)
+	tokens, offsets = tokenizeInto(buf, tok, end, tokens, offsets)
+	//if cap(tokens) != estimate {
+	//	fmt.Println("reallocated")
+	//}
+	return tokens, offsets
+}
+
+// tokenizeInto appends the tokens and offsets for buf to tokens and offsets.
+func tokenizeInto(buf []byte, tok lineTokenizer, end tokenizer.Token, tokens []tokenizer.Token, offsets []int) ([]tokenizer.Token, []int) {
 	for offset := 0; ; {
 		token, length := tok.Line(buf[offset:])
 		tokens = append(tokens, token)
 		offsets = append(offsets, offset)
-		if token == tok.E {
+		if token == end {
 			break
 		}
 		offset += length
 	}
-	//if cap(tokens) != estimate {
-	//	fmt.Println("reallocated")
-	//}
-	parsed := parser.Parse(tokens)
+	return tokens, offsets
+}
 
+// parallelLines is the number of lines at which parsePages starts parsing in
+// parallel. Below it, starting goroutines costs more than it saves.
+const parallelLines = 100000
+
+func parsePages(buf []byte) (pages []*Page, nLines int, err error) {
+	pages, stats, err := newOptions().parse(buf)
+	return pages, stats.LineCount, err
+}
+
+// parse returns the pages in buf and the statistics, which are never nil.
+func (o *options) parse(buf []byte) (pages []*Page, stats *Statistics, err error) {
+	stats = &Statistics{}
+	if o.err != nil {
+		return nil, stats, o.err
+	}
+	buf = normalize(buf, o.keepCRLF, stats)
+	tokens, offsets := o.tokenize(buf)
+	machine := o.alphabet().Map(tokens)
+	var parsed []parser.Page
+	if len(tokens) < parallelLines {
+		parsed, err = parser.ParseTokens(machine)
+	} else {
+		parsed, err = parser.ParseParallel(machine, runtime.GOMAXPROCS(0))
+	}
+	if err != nil {
+		return nil, stats, err
+	}
+
+	stats.LineCount = len(tokens)
+	return makePages(o, buf, offsets, tokens, parsed), stats, nil
+}
+
+// makePages returns the pages for parsed, which were found in buf.
+func makePages(o *options, buf []byte, offsets []int, tokens []tokenizer.Token, parsed []parser.Page) []*Page {
 	backing := make([]Page, len(parsed))
 	offsets = append(offsets, len(buf))
 	for i, p := range parsed {
-		backing[i].titles = makeTitles(buf, offsets, p.Head)
-		backing[i].file = buf
-		backing[i].offsets = offsets[p.Body.Low : p.Body.High+1]
-		backing[i].all = offsets[p.Head.Low : p.Body.High+1]
-		backing[i].line = p.Head.Low + 1
-		backing[i].index = i
+		backing[i].set(o, buf, offsets, tokens, p, i)
 	}
-	pages = make([]*Page, len(backing))
+	pages := make([]*Page, len(backing))
 	for i := range backing {
 		pages[i] = &backing[i]
 	}
-
-	return pages, len(tokens)
+	return pages
 }
 
-func pages() (pages []*Page, nLines int, err error) {
-	buf, err := storage.Load()
-	if err != nil {
-		return nil, 0, err
-	}
-	pages, nLines = pagesFrom(buf)
-	return pages, nLines, nil
-}
-
-// Pages returns the pages.
-func Pages() ([]*Page, error) {
-	pages, _, err := pages()
+// Pages returns the pages. Options change where they are read from and how
+// they are found.
+func Pages(opts ...Option) ([]*Page, error) {
+	pages, _, err := loadPages(newOptions(opts...))
 	return pages, err
 }
 
@@ -315,12 +417,21 @@ type Statistics struct {
 	// LineCount is the number of lines in the file. Not all of those lines
 	// are necessarily part of a page.
 	LineCount int
+
+	// BOM is whether the file started with a UTF-8 byte order mark, which
+	// is not part of any page.
+	BOM bool
+
+	// CRLFLines is the number of lines ending in CRLF. They end in LF in
+	// the pages, unless WithOriginalLineEndings is used.
+	CRLFLines int
+
+	// InvalidUTF8 contains the numbers (one based) of the lines that are not
+	// valid UTF-8. They are read anyway.
+	InvalidUTF8 []int
 }
 
-// PagesStatistics returns the pages and statistics.
-func PagesStatistics() ([]*Page, *Statistics, error) {
-	pages, nLines, err := pages()
-	return pages, &Statistics{
-		LineCount: nLines,
-	}, err
+// PagesStatistics returns the pages and statistics. Options are as for Pages.
+func PagesStatistics(opts ...Option) ([]*Page, *Statistics, error) {
+	return loadPages(newOptions(opts...))
 }
@@ -1,8 +1,12 @@
 package thefile
 
 import (
+	"flag"
 	"reflect"
 	"testing"
+
+	"sethwklein.net/thefile/parser"
+	"sethwklein.net/thefile/tokenizer"
 )
 
 func TestAddress(t *testing.T) {
@@ -15,7 +19,10 @@ two content
 lines
 
 `)
-	pages, _ := pagesFrom(buf)
+	pages, _, err := parsePages(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
 	want := []int{1, 4}
 	var got []int
 	for _, page := range pages {
@@ -26,15 +33,56 @@ lines
 	}
 }
 
+var testFile = flag.String("file", "",
+	"file for benchmarks to read instead of the one in storage")
+
+// testSource returns the source of the large file benchmarks use.
+func testSource() Source {
+	if *testFile == "" {
+		return Storage
+	}
+	return FileSource(*testFile)
+}
+
 var ps []*Page
 
 func BenchmarkPages(b *testing.B) {
+	source := testSource()
 	for i := 0; i < b.N; i++ {
 		var err error
-		ps, err = Pages()
+		ps, err = PagesFrom(source)
 		if err != nil {
 			b.Error(err)
 			return
 		}
 	}
 }
+
+func TestAlternates(t *testing.T) {
+	buf := []byte("----one\n----two\n----one\n----\n\nbody\n")
+	tokens := []tokenizer.Token{'t', 'a', 'a', 'a', 'c', 'o', 'e'}
+	offsets := []int{0, 8, 16, 24, 29, 30, 35, 35}
+	parsed, err := parser.ParseWith(tokenizer.Default, tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages := makePages(newOptions(), buf, offsets[:len(tokens)], tokens, parsed)
+	if len(pages) != 1 {
+		t.Fatalf("expected one page, got %d", len(pages))
+	}
+	want := []string{"two", "one"}
+	if got := pages[0].Alternates(); !reflect.DeepEqual(want, got) {
+		t.Errorf("\nwant: %v\ngot:  %v\n", want, got)
+	}
+	want = []string{"one", "two"}
+	if got := pages[0].Tags(); !reflect.DeepEqual(want, got) {
+		t.Errorf("\nwant: %v\ngot:  %v\n", want, got)
+	}
+	index := NewIndex(pages)
+	if got := index.Alternate("one"); len(got) != 1 || got[0] != pages[0] {
+		t.Errorf("wrong pages for alternate: %v", got)
+	}
+	if got := index.Alternate("body"); got != nil {
+		t.Errorf("unexpected pages for alternate: %v", got)
+	}
+}
@@ -7,7 +7,7 @@ import (
 var hash []byte
 
 func BenchmarkHash(b *testing.B) {
-	pages, err := Pages()
+	pages, err := PagesFrom(testSource())
 	if err != nil {
 		b.Error(err)
 		return
@@ -16,7 +16,7 @@ func BenchmarkHash(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		for j := 0; j < 1; j++ {
 			for _, page := range pages {
-				hash = page.Hash()
+				hash = page.HashRaw()
 			}
 		}
 	}
@@ -10,6 +10,7 @@ type Index struct {
 	named   map[string][]*Page
 	tagged  map[string][]*Page
 	in      map[string][]*Page
+	alt     map[string][]*Page
 }
 
 // Pages returns the pages used to create index.
@@ -96,11 +97,17 @@ func (index *Index) In(thing string) []*Page {
 	return index.in[thing]
 }
 
+// Alternate returns all pages with title on an alternate title line.
+func (index *Index) Alternate(title string) []*Page {
+	return index.alt[title]
+}
+
 func NewIndex(pages []*Page) *Index {
 	address := make(map[int]*Page)
 	named := make(map[string][]*Page)
 	tagged := make(map[string][]*Page)
 	in := make(map[string][]*Page)
+	alt := make(map[string][]*Page)
 	for _, page := range pages {
 		name, anonymous := page.Name()
 		if !anonymous {
@@ -115,6 +122,9 @@ func NewIndex(pages []*Page) *Index {
 		for _, thing := range page.In() {
 			in[thing] = append(in[thing], page)
 		}
+		for _, title := range page.Alternates() {
+			alt[title] = append(alt[title], page)
+		}
 	}
 	return &Index{
 		pages:   pages,
@@ -122,5 +132,13 @@ func NewIndex(pages []*Page) *Index {
 		named:   named,
 		tagged:  tagged,
 		in:      in,
+		alt:     alt,
 	}
 }
+
+// NewTreeIndex is like NewIndex, but indexes the children of pages, and
+// their children, and so on, as well as pages. Pages returns them all, as
+// from Flatten.
+func NewTreeIndex(pages []*Page) *Index {
+	return NewIndex(Flatten(pages))
+}
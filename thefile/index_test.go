@@ -2,14 +2,12 @@ package thefile
 
 import (
 	"testing"
-
-	"sethwklein.net/thefile/thefile"
 )
 
 var index *Index
 
 func BenchmarkNew(b *testing.B) {
-	pages, err := thefile.Pages()
+	pages, err := PagesFrom(testSource())
 	if err != nil {
 		b.Error(err)
 		return
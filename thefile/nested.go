@@ -0,0 +1,110 @@
+package thefile
+
+import (
+	"sethwklein.net/thefile/parser"
+	"sethwklein.net/thefile/tokenizer"
+)
+
+// Some of my long pages have sections that deserve to be pages of their own.
+// I mark their titles with one more '-' than the title marker for each level
+// of nesting, or in other dialects, one more of the first byte of the marker.
+
+// Parent returns the page whose body contains page, or nil for top level
+// pages.
+func (page *Page) Parent() *Page {
+	return page.parent
+}
+
+// Children returns the pages in page's body. Page's body still contains them.
+func (page *Page) Children() []*Page {
+	return page.children
+}
+
+// Depth returns 0 for top level pages, 1 for their children, and so on.
+func (page *Page) Depth() int {
+	return page.depth
+}
+
+// makeChildren fills in the children of page from node.
+func (page *Page) makeChildren(o *options, buf []byte, offsets []int, tokens []tokenizer.Token, node parser.Node) {
+	if len(node.Children) < 1 {
+		return
+	}
+	backing := make([]Page, len(node.Children))
+	page.children = make([]*Page, len(backing))
+	for i, c := range node.Children {
+		child := &backing[i]
+		child.depth = page.depth + 1
+		child.parent = page
+		child.set(o, buf, offsets, tokens, c.Page, i)
+		child.makeChildren(o, buf, offsets, tokens, c)
+		page.children[i] = child
+	}
+}
+
+func parseNestedPages(buf []byte) ([]*Page, error) {
+	return newOptions().parseNested(buf)
+}
+
+// parseNested is like parse, but for NestedPages.
+func (o *options) parseNested(buf []byte) ([]*Page, error) {
+	if o.err != nil {
+		return nil, o.err
+	}
+	buf = normalize(buf, o.keepCRLF, &Statistics{})
+	tokens, offsets := o.tokenize(buf)
+	machine := o.alphabet().Map(tokens)
+	depths := make([]int, len(tokens))
+	for i, token := range machine {
+		if token != 't' {
+			continue
+		}
+		if depth := o.dialect.depth(buf[offsets[i]:offsets[i+1]]); depth > 0 {
+			depths[i] = depth
+		}
+	}
+	nodes, err := parser.ParseNested(machine, depths)
+	if err != nil {
+		return nil, err
+	}
+	parsed := make([]parser.Page, len(nodes))
+	for i, node := range nodes {
+		parsed[i] = node.Page
+	}
+	pages := makePages(o, buf, offsets, tokens, parsed)
+	offsets = append(offsets, len(buf))
+	for i, page := range pages {
+		page.makeChildren(o, buf, offsets, tokens, nodes[i])
+	}
+	return pages, nil
+}
+
+// NestedPages is like Pages, but also finds the pages in the bodies of pages,
+// which have deeper title markers. The top level pages are returned. Options
+// are as for Pages.
+func NestedPages(opts ...Option) ([]*Page, error) {
+	o := newOptions(opts...)
+	if o.err != nil {
+		return nil, o.err
+	}
+	buf, err := o.source.Load()
+	if err != nil {
+		return nil, err
+	}
+	return o.parseNested(buf)
+}
+
+// Flatten returns pages and all their descendants, each followed by its
+// children. If pages are sorted by address, so is the result.
+func Flatten(pages []*Page) []*Page {
+	var all []*Page
+	var walk func([]*Page)
+	walk = func(pages []*Page) {
+		for _, page := range pages {
+			all = append(all, page)
+			walk(page.children)
+		}
+	}
+	walk(pages)
+	return all
+}
@@ -0,0 +1,65 @@
+package thefile
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNestedPages(t *testing.T) {
+	buf := []byte(`----one
+
+one content
+
+-----one dot one
+
+nested content
+
+------one dot one dot one
+
+-----one dot two
+
+----two
+
+two content
+`)
+	pages, err := parseNestedPages(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type entry struct {
+		Title          string
+		Depth, Address int
+		Parent         string
+	}
+	want := []entry{
+		{"one", 0, 1, ""},
+		{"one dot one", 1, 5, "one"},
+		{"one dot one dot one", 2, 9, "one dot one"},
+		{"one dot two", 1, 11, "one"},
+		{"two", 0, 13, ""},
+	}
+	var got []entry
+	for _, page := range Flatten(pages) {
+		name, _ := page.Name()
+		e := entry{name, page.Depth(), page.Address(), ""}
+		if parent := page.Parent(); parent != nil {
+			e.Parent, _ = parent.Name()
+		}
+		got = append(got, e)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("\nwant: %v\ngot:  %v\n", want, got)
+	}
+
+	if len(pages) != 2 || len(pages[0].Children()) != 2 {
+		t.Fatalf("wrong tree: %v", got)
+	}
+	if child := pages[0].Children()[1]; child.Index() != 1 {
+		t.Errorf("child index: want 1, got %d", child.Index())
+	}
+	index := NewTreeIndex(pages)
+	if got := index.AllNamed("one dot one dot one"); len(got) != 1 ||
+		got[0] != pages[0].Children()[0].Children()[0] {
+		t.Errorf("wrong pages for title: %v", got)
+	}
+}
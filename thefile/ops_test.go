@@ -7,7 +7,7 @@ import (
 var list []*Page
 
 func BenchmarkIntersect(b *testing.B) {
-	pages, err := Pages()
+	pages, err := PagesFrom(testSource())
 	if err != nil {
 		b.Error(err)
 		return
@@ -23,7 +23,7 @@ func BenchmarkIntersect(b *testing.B) {
 // BUG(sk): these are pretty half hearted tests
 
 func TestSubtract(t *testing.T) {
-	pages, err := Pages()
+	pages, err := PagesFrom(testSource())
 	if err != nil {
 		t.Error(err)
 		return
@@ -42,7 +42,7 @@ func TestSubtract(t *testing.T) {
 }
 
 func TestIntersect(t *testing.T) {
-	pages, err := Pages()
+	pages, err := PagesFrom(testSource())
 	if err != nil {
 		t.Error(err)
 		return
@@ -0,0 +1,168 @@
+package thefile
+
+import (
+	"sethwklein.net/thefile/parser"
+	"sethwklein.net/thefile/tokenizer"
+)
+
+// Option changes how Pages and the functions like it find pages.
+type Option func(*options)
+
+// options are the settings Options change.
+type options struct {
+	source    Source
+	tokenizer tokenizer.Tokenizer
+	dialect   Dialect
+	// prefix overrides the length of the dialect's marker if not 0.
+	prefix   int
+	capacity int
+	keepCRLF bool
+
+	// tokenizerSet is whether WithTokenizer was used.
+	tokenizerSet bool
+	// err is returned by everything that uses the options, if not nil.
+	err error
+}
+
+// newOptions returns the defaults changed by opts.
+func newOptions(opts ...Option) *options {
+	o := &options{
+		source:    Storage,
+		tokenizer: tokenizer.Default,
+		dialect:   DefaultDialect,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	o.err = o.dialect.check()
+	if o.err == nil && o.tokenizerSet && !o.dialect.Tokenized {
+		o.err = DialectError{o.dialect,
+			"WithTokenizer needs a Tokenized dialect"}
+	}
+	return o
+}
+
+// WithSource makes Pages read source instead of Storage.
+func WithSource(source Source) Option {
+	return func(o *options) {
+		o.source = source
+	}
+}
+
+// WithTokenizer makes Pages use t instead of tokenizer.Default for Tokenized
+// dialects. Using it with other dialects is an error. If t's alternate title
+// token is its title token, as it was before alternate titles, pages have no
+// Alternates.
+func WithTokenizer(t tokenizer.Tokenizer) Option {
+	return func(o *options) {
+		o.tokenizer = t
+		o.tokenizerSet = true
+	}
+}
+
+// WithTitlePrefix sets the number of bytes before the title on a title line,
+// which is the length of the dialect's marker by default.
+func WithTitlePrefix(n int) Option {
+	return func(o *options) {
+		o.prefix = n
+	}
+}
+
+// WithDialect makes Pages read titles written in d instead of
+// DefaultDialect. If d can't be used, Pages returns a DialectError.
+func WithDialect(d Dialect) Option {
+	return func(o *options) {
+		o.dialect = d
+	}
+}
+
+// WithOriginalLineEndings makes Pages keep CRLF line endings instead of
+// replacing them with LF, for tools that write the file back. Titles never
+// include the CR, but bodies and hashes do.
+func WithOriginalLineEndings() Option {
+	return func(o *options) {
+		o.keepCRLF = true
+	}
+}
+
+// WithCapacityHint makes Pages allocate room for the given number of lines
+// instead of estimating it from a sample of the file.
+func WithCapacityHint(lines int) Option {
+	return func(o *options) {
+		o.capacity = lines
+	}
+}
+
+// lineTokenizer is what tokenizer.Tokenizer and Dialect have in common.
+type lineTokenizer interface {
+	Line(buf []byte) (tokenizer.Token, int)
+}
+
+// lines returns what tokenizes lines for o and the end of file token.
+func (o *options) lines() (lineTokenizer, tokenizer.Token) {
+	if !o.dialect.Tokenized {
+		// Dialect.Line reads CRLF and unterminated lines itself
+		return o.dialect, parser.DefaultAlphabet.E
+	}
+	var tok lineTokenizer = &o.tokenizer
+	if o.keepCRLF {
+		tok = &crlfTokenizer{tok: tok}
+	}
+	if o.dialect.Unterminated == UnterminatedOther {
+		tok = &unterminatedOther{tok, o.alphabet()}
+	}
+	return tok, o.tokenizer.E
+}
+
+// alphabet returns the tokens o's tokenizer produces.
+func (o *options) alphabet() parser.Alphabet {
+	if !o.dialect.Tokenized {
+		return parser.DefaultAlphabet
+	}
+	return parser.NewAlphabet(o.tokenizer)
+}
+
+// alternate returns the token for alternate titles, or 0 if they are not
+// distinguished from titles.
+func (o *options) alternate() tokenizer.Token {
+	alphabet := o.alphabet()
+	if alphabet.A == alphabet.T {
+		return 0
+	}
+	return alphabet.A
+}
+
+// title returns the title from line, which has the given token and is in a
+// page depth levels deep. Unless the dialect is Tokenized, the depth is that
+// of the line's marker, so deeper markers give the same titles to Pages as
+// to NestedPages. Tokenized dialects keep reading them as the file always
+// has.
+func (o *options) title(line []byte, token tokenizer.Token, depth int) []byte {
+	if !o.dialect.Tokenized {
+		if d := o.dialect.depth(line); d > 0 {
+			depth = d
+		}
+	}
+	prefix := len(o.dialect.Marker)
+	if o.prefix != 0 {
+		prefix = o.prefix
+	} else if token == o.alternate() && o.dialect.AlternateMarker != "" {
+		prefix = len(o.dialect.AlternateMarker)
+	}
+	return o.dialect.title(line, prefix+depth)
+}
+
+// titleLine returns the line for title, and whether it reads back the same.
+func (o *options) titleLine(title string, alternate bool) ([]byte, bool) {
+	marker := o.dialect.Marker
+	if alternate && o.dialect.AlternateMarker != "" {
+		marker = o.dialect.AlternateMarker
+	}
+	line := []byte(marker + title + "\n")
+	tok, _ := o.lines()
+	token, length := tok.Line(line)
+	if length != len(line) || o.alphabet().Token(token) != 't' {
+		return nil, false
+	}
+	return line, string(o.title(line, token, 0)) == title
+}
@@ -0,0 +1,114 @@
+package thefile
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"sethwklein.net/thefile/tokenizer"
+)
+
+func TestOptions(t *testing.T) {
+	buf := []byte("----: one\n----=:two\n\nbody\n")
+	source := WithSource(BytesSource(buf))
+
+	pages, err := Pages(source, WithCapacityHint(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _, err := parsePages(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(expected, pages) {
+		t.Errorf("\nexpected: %v\nactual:   %v", expected, pages)
+	}
+
+	pages, err = Pages(source, WithTitlePrefix(6))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := []string{"one", "two"}, pages[0].Tags(); !reflect.DeepEqual(want, got) {
+		t.Errorf("\nwant: %v\ngot:  %v\n", want, got)
+	}
+	if want, got := []string{"two"}, pages[0].Alternates(); !reflect.DeepEqual(want, got) {
+		t.Errorf("\nwant: %v\ngot:  %v\n", want, got)
+	}
+
+	// alternate titles read as titles, as they were before they existed
+	tok := tokenizer.Default
+	tok.A = 't'
+	pages, err = Pages(source, WithTitlePrefix(6), WithTokenizer(tok))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := []string{"one", "two"}, pages[0].Tags(); !reflect.DeepEqual(want, got) {
+		t.Errorf("\nwant: %v\ngot:  %v\n", want, got)
+	}
+	if got := pages[0].Alternates(); got != nil {
+		t.Errorf("unexpected alternates: %v", got)
+	}
+}
+
+func TestTokenizerTokens(t *testing.T) {
+	buf := []byte("----one\n----=two\n\nbody\n")
+	expected, _, err := parsePages(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	upper := tokenizer.Tokenizer{C: 'C', T: 'T', A: 'A', O: 'O', E: 'E'}
+	pages, err := PagesFrom(BytesSource(buf), WithTokenizer(upper))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 1 || !reflect.DeepEqual(expected[0].Tags(), pages[0].Tags()) ||
+		!reflect.DeepEqual(expected[0].Alternates(), pages[0].Alternates()) {
+		t.Errorf("\nexpected: %v\nactual:   %v", expected, pages)
+	}
+}
+
+// TestOptionsEverywhere checks that the functions that find pages without
+// Pages use their options.
+func TestOptionsEverywhere(t *testing.T) {
+	buf := []byte("----: one\n----=:two\n\nbody\n")
+	opt := WithTitlePrefix(6)
+	want := []string{"one", "two"}
+	check := func(name string, pages []*Page, err error) {
+		t.Helper()
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			return
+		}
+		if len(pages) != 1 {
+			t.Errorf("%s: expected one page, got %d", name, len(pages))
+		} else if got := pages[0].Tags(); !reflect.DeepEqual(want, got) {
+			t.Errorf("%s:\nwant: %v\ngot:  %v", name, want, got)
+		}
+	}
+
+	pages, err := PagesFrom(BytesSource(buf), opt)
+	check("Pages", pages, err)
+
+	update, err := Reparse(pages, buf, len(buf)-5, len(buf), []byte("more\n"), opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("Reparse", update.Pages, nil)
+
+	_, pages, err = Spans(buf, opt)
+	check("Spans", pages, err)
+
+	pages = nil
+	err = StreamPages(bytes.NewReader(buf), func(page *Page) error {
+		pages = append(pages, page)
+		return nil
+	}, opt)
+	check("StreamPages", pages, err)
+
+	ed, err := NewEditor(buf, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ed.ReplaceBody(0, []byte("more\n"))
+	check("Editor", ed.Pages(), err)
+}
@@ -0,0 +1,212 @@
+package thefile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"sethwklein.net/thefile/parser"
+	"sethwklein.net/thefile/tokenizer"
+)
+
+// Update is the result of Reparse.
+type Update struct {
+	// Buffer is the edited file, which the offsets of Pages refer to.
+	Buffer []byte
+
+	// Pages are the pages in Buffer, the same as Pages would find.
+	Pages []*Page
+
+	// Removed contains the indexes of the old pages that are gone.
+	Removed []int
+
+	// Added contains the indexes of the new pages that weren't there.
+	Added []int
+
+	// Changed contains the indexes of the new pages that replace old pages
+	// in the same place but are different. Pages not in Added or Changed
+	// are the same as before, except that they may have moved.
+	Changed []int
+}
+
+// lineEnd returns the offset just past the line starting at offset.
+func lineEnd(buf []byte, offset int) int {
+	end := bytes.IndexByte(buf[offset:], '\n')
+	if end < 0 {
+		return len(buf)
+	}
+	return offset + end + 1
+}
+
+var errNotNormalized = errors.New(
+	"reparse: buf has a byte order mark or CRLF line endings, unlike what Pages reads")
+
+// same returns whether a and b have the same titles and bytes.
+func same(a, b *Page) bool {
+	if len(a.titles) != len(b.titles) || !bytes.Equal(a.All(), b.All()) {
+		return false
+	}
+	for i := range a.titles {
+		if a.titles[i] != b.titles[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Reparse returns the pages after replacing buf[low:high] with text, where
+// pages are the pages in buf and 0 <= low <= high <= len(buf). It only parses again from a head before the
+// edit to a head after it, since whatever is outside is parsed the same way
+// either way. Buf is not changed. Options are as for Pages, and should be the
+// ones pages were found with.
+//
+// Unless given WithOriginalLineEndings, buf must be as Pages reads it,
+// without a byte order mark or CRLF line endings, and text is changed the
+// same way, including a CR at the end of buf[:low] before an LF starting
+// text or buf[high:], so Buffer may not be buf with text put in place.
+func Reparse(pages []*Page, buf []byte, low, high int, text []byte, opts ...Option) (*Update, error) {
+	return newOptions(opts...).reparse(pages, buf, low, high, text)
+}
+
+// reparse is Reparse with o.
+func (o *options) reparse(pages []*Page, buf []byte, low, high int, text []byte) (*Update, error) {
+	if o.err != nil {
+		return nil, o.err
+	}
+	if low < 0 || low > high || high > len(buf) {
+		return nil, fmt.Errorf("reparse: edit %d:%d is not within buf, which is %d bytes",
+			low, high, len(buf))
+	}
+	if !o.keepCRLF {
+		if bytes.HasPrefix(buf, bom) || bytes.Contains(buf, crlf) {
+			return nil, errNotNormalized
+		}
+		// take in the bytes that could make CRLF with text, and
+		// replace it in text
+		if low > 0 && buf[low-1] == '\r' {
+			low--
+			text = append([]byte{'\r'}, text...)
+		}
+		if high < len(buf) && buf[high] == '\n' {
+			high++
+			text = append(text[:len(text):len(text)], '\n')
+		}
+		if low == 0 {
+			text = bytes.TrimPrefix(text, bom)
+		}
+		text = bytes.ReplaceAll(text, crlf, []byte{'\n'})
+	}
+	edited := make([]byte, 0, len(buf)-(high-low)+len(text))
+	edited = append(edited, buf[:low]...)
+	edited = append(edited, text...)
+	edited = append(edited, buf[high:]...)
+	delta := len(text) - (high - low)
+
+	// first is the first page parsed again and last is the first page
+	// after that which isn't. Heads are kept as places to start and stop
+	// only if the edit can't change them or the clears around them.
+	first, last := 0, len(pages)
+	start, startLine := 0, 0
+	for i, page := range pages {
+		if page.all[0] >= low {
+			break
+		}
+		if page.head < 1 {
+			continue
+		}
+		// the clear after the head must be entirely before the edit
+		clear := page.all[page.head]
+		if clear < len(buf) && lineEnd(buf, clear) <= low {
+			first, start, startLine = i, page.all[0], page.line-1
+		}
+	}
+	end := len(buf)
+	for i := first + 1; i < len(pages); i++ {
+		page := pages[i]
+		if page.head < 1 {
+			continue
+		}
+		// the clear before the head must be entirely after the edit,
+		// including the newline before it.
+		head := page.all[0]
+		clear := bytes.LastIndexByte(buf[:head-1], '\n') + 1
+		if clear > high {
+			last, end = i, head
+			break
+		}
+	}
+
+	var tokens []tokenizer.Token
+	var offsets []int
+	if start == 0 {
+		// skip any byte order mark, as Pages does
+		tokens, offsets = o.tokenize(edited[:end+delta])
+	} else {
+		tok, endToken := o.lines()
+		tokens, offsets = tokenize(edited[start:end+delta], tok, endToken, 0)
+	}
+	parsed, err := parser.ParseTokens(o.alphabet().Map(tokens))
+	if err != nil {
+		return nil, err
+	}
+	for i := range offsets {
+		offsets[i] += start
+	}
+
+	update := &Update{Buffer: edited}
+	update.Pages = make([]*Page, 0, first+len(parsed)+len(pages)-last)
+
+	for _, old := range pages[:first] {
+		page := *old
+		page.file = edited
+		update.Pages = append(update.Pages, &page)
+	}
+
+	backing := make([]Page, len(parsed))
+	for i, p := range parsed {
+		backing[i].set(o, edited, offsets, tokens, p, first+i)
+		backing[i].line += startLine
+		update.Pages = append(update.Pages, &backing[i])
+	}
+
+	lineDelta := 0
+	if last < len(pages) {
+		lineDelta = startLine + len(tokens) - 1 - (pages[last].line - 1)
+	}
+	indexDelta := first + len(parsed) - last
+	for _, old := range pages[last:] {
+		page := *old
+		page.file = edited
+		page.all = make([]int, len(old.all))
+		for i, offset := range old.all {
+			page.all[i] = offset + delta
+		}
+		page.offsets = page.all[len(old.all)-len(old.offsets):]
+		page.line += lineDelta
+		page.index += indexDelta
+		update.Pages = append(update.Pages, &page)
+	}
+
+	// compare the pages parsed again with the ones they replaced, matching
+	// from both ends.
+	olds, news := pages[first:last], update.Pages[first:first+len(parsed)]
+	for len(olds) > 0 && len(news) > 0 && same(olds[0], news[0]) {
+		olds, news = olds[1:], news[1:]
+	}
+	for len(olds) > 0 && len(news) > 0 &&
+		same(olds[len(olds)-1], news[len(news)-1]) {
+		olds, news = olds[:len(olds)-1], news[:len(news)-1]
+	}
+	for i := range news {
+		if i < len(olds) {
+			update.Changed = append(update.Changed, news[i].index)
+		} else {
+			update.Added = append(update.Added, news[i].index)
+		}
+	}
+	for i := len(news); i < len(olds); i++ {
+		update.Removed = append(update.Removed, olds[i].index)
+	}
+
+	return update, nil
+}
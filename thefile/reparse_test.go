@@ -0,0 +1,140 @@
+package thefile
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// randomFile returns a file with lines chosen from a few that make many
+// corner cases likely.
+func randomFile(r *rand.Rand, lines int) []byte {
+	choices := []string{"\n", "\n", "----one\n", "----two\n", "----=alt\n",
+		"other\n", " \n"}
+	buf := &bytes.Buffer{}
+	for i := 0; i < lines; i++ {
+		buf.WriteString(choices[r.Intn(len(choices))])
+	}
+	if r.Intn(4) == 0 {
+		buf.WriteString("no newline")
+	}
+	return buf.Bytes()
+}
+
+func samePages(t *testing.T, expected, actual []*Page) bool {
+	if len(expected) != len(actual) {
+		t.Errorf("expected %d pages, got %d", len(expected), len(actual))
+		return false
+	}
+	for i := range expected {
+		e, a := expected[i], actual[i]
+		if !reflect.DeepEqual(e.titles, a.titles) ||
+			!reflect.DeepEqual(e.alternates, a.alternates) ||
+			!bytes.Equal(e.All(), a.All()) ||
+			!reflect.DeepEqual(e.Lines(), a.Lines()) ||
+			e.Address() != a.Address() || e.Index() != a.Index() ||
+			e.head != a.head {
+			t.Errorf("page %d differs", i)
+			return false
+		}
+	}
+	return true
+}
+
+func TestReparse(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	toCRLF := func(buf []byte) []byte {
+		return bytes.ReplaceAll(buf, []byte{'\n'}, []byte("\r\n"))
+	}
+	for n := 0; n < 3000; n++ {
+		buf := randomFile(r, r.Intn(40))
+		text := randomFile(r, r.Intn(4))
+		var opts []Option
+		switch n % 3 {
+		case 1:
+			// CRLF in text is replaced, as Pages does
+			text = toCRLF(text)
+		case 2:
+			// or kept, with the CRLF in buf
+			buf, text = toCRLF(buf), toCRLF(text)
+			opts = append(opts, WithOriginalLineEndings())
+		}
+		o := newOptions(opts...)
+		pages, _, err := o.parse(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		low := r.Intn(len(buf) + 1)
+		high := low + r.Intn(len(buf)-low+1)
+		if r.Intn(2) == 0 {
+			high = low + r.Intn((len(buf)-low)/8+1)
+		}
+
+		update, err := Reparse(pages, buf, low, high, text, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, _, err := o.parse(update.Buffer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !samePages(t, expected, update.Pages) {
+			t.Fatalf("\nbuf:  %q\nedit: %d:%d %q", buf, low, high, text)
+		}
+		if len(pages)-len(update.Removed)+len(update.Added) != len(expected) {
+			t.Fatalf("\nbuf:  %q\nedit: %d:%d %q\nadded %v, removed %v",
+				buf, low, high, text, update.Added, update.Removed)
+		}
+		// Buffer is as Pages reads it
+		if opts == nil && !bytes.Equal(normalize(update.Buffer, false, &Statistics{}), update.Buffer) {
+			t.Fatalf("\nbuf:  %q\nedit: %d:%d %q\nbuffer: %q", buf, low, high,
+				text, update.Buffer)
+		}
+	}
+
+	// a CR before the edit and an LF in text make CRLF
+	buf := []byte("----one\n\nx\r")
+	pages, _, err := parsePages(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	update, err := Reparse(pages, buf, len(buf), len(buf), []byte("\ny\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "----one\n\nx\ny\n"; string(update.Buffer) != expected {
+		t.Errorf("\nexpected: %q\nactual:   %q", expected, update.Buffer)
+	}
+
+	// the edit must be within buf
+	for _, edit := range [][2]int{{-1, 0}, {3, 2}, {0, len(buf) + 1}} {
+		if _, err := Reparse(pages, buf, edit[0], edit[1], nil); err == nil {
+			t.Errorf("edit %d:%d: expected error", edit[0], edit[1])
+		}
+	}
+
+	// buf must be as Pages reads it
+	buf = []byte("----one\r\n\r\nbody\r\n")
+	if _, err := Reparse(nil, buf, 0, 0, nil); err != errNotNormalized {
+		t.Errorf("expected errNotNormalized, got %v", err)
+	}
+}
+
+func TestReparseChanges(t *testing.T) {
+	buf := []byte("----one\n\nbody\n\n----two\n\nbody\n\n----three\n\nbody\n")
+	pages, _, err := parsePages(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := bytes.Index(buf, []byte("----two"))
+	update, err := Reparse(pages, buf, at, at, []byte("----new\n\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(update.Added, []int{1}) || update.Removed != nil ||
+		update.Changed != nil {
+		t.Errorf("added %v, removed %v, changed %v", update.Added,
+			update.Removed, update.Changed)
+	}
+}
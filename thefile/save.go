@@ -0,0 +1,179 @@
+package thefile
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Tools that write the file must not clobber edits made in an editor between
+// when they load it and when they save it.
+
+// ConflictError is returned by Save when the file changed after it was
+// loaded. Pages are identified by Hash64.
+type ConflictError struct {
+	Path string
+
+	// Added contains the pages on disk that weren't loaded, including new
+	// versions of changed pages.
+	Added []string
+
+	// Removed contains the pages that were loaded but aren't on disk,
+	// including old versions of changed pages.
+	Removed []string
+}
+
+// Error satisfies the error interface.
+func (err ConflictError) Error() string {
+	return fmt.Sprintf("%s changed since it was loaded: %d pages added or changed, %d removed or changed",
+		err.Path, len(err.Added), len(err.Removed))
+}
+
+// Snapshot is a file as it was loaded, so changes can be saved to it only
+// if it hasn't changed since.
+type Snapshot struct {
+	Path   string
+	Buffer []byte
+
+	// Backups is the number of previous versions Save keeps, as Path.1,
+	// the newest, through Path.Backups, named after the file Path links to
+	// if it is a symbolic link.
+	Backups int
+
+	hash [sha256.Size]byte
+}
+
+// LoadSnapshot reads the file at path.
+func LoadSnapshot(path string) (*Snapshot, error) {
+	return LoadSnapshotFrom(FileSource(path), path)
+}
+
+// LoadSnapshotFrom loads source, which is saved to the file at path. The
+// storage package only loads, so to save the file in storage, pass Storage
+// and the path of the file in the configured storage location. If source
+// isn't the file at path, the first Save returns a ConflictError.
+func LoadSnapshotFrom(source Source, path string) (*Snapshot, error) {
+	buf, err := source.Load()
+	if err != nil {
+		return nil, err
+	}
+	return &Snapshot{Path: path, Buffer: buf, hash: sha256.Sum256(buf)}, nil
+}
+
+// conflict returns the ConflictError for the file changing from loaded to
+// current.
+func conflict(path string, loaded, current []byte) error {
+	err := ConflictError{Path: path}
+	hashes := func(buf []byte) map[string]bool {
+		set := make(map[string]bool)
+		pages, _, parseErr := parsePages(buf)
+		if parseErr != nil {
+			return set
+		}
+		for _, page := range pages {
+			set[page.Hash64()] = true
+		}
+		return set
+	}
+	before, after := hashes(loaded), hashes(current)
+	for hash := range after {
+		if !before[hash] {
+			err.Added = append(err.Added, hash)
+		}
+	}
+	for hash := range before {
+		if !after[hash] {
+			err.Removed = append(err.Removed, hash)
+		}
+	}
+	sort.Strings(err.Added)
+	sort.Strings(err.Removed)
+	return err
+}
+
+// beforeLastCheck is called by Save just before it checks the file for the
+// last time, so tests can change it there.
+var beforeLastCheck = func() {}
+
+// check returns the file at path, or a ConflictError if it isn't the file s
+// loaded.
+func (s *Snapshot) check(path string) ([]byte, error) {
+	current, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if sha256.Sum256(current) != s.hash {
+		return nil, conflict(s.Path, s.Buffer, current)
+	}
+	return current, nil
+}
+
+// Save replaces the file with buf, unless it changed since it was loaded,
+// in which case it returns a ConflictError. The new file is written beside
+// the old one and renamed over it, so the file is always either entirely old
+// or entirely new. If Path is a symbolic link, the file it links to is
+// replaced, and the link is kept. Afterward, s is a snapshot of buf.
+func (s *Snapshot) Save(buf []byte) error {
+	path, err := filepath.EvalSymlinks(s.Path)
+	if err != nil {
+		return err
+	}
+	if _, err := s.check(path); err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	dir, base := filepath.Split(path)
+	temp, err := os.CreateTemp(dir, "."+base+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	_, err = temp.Write(buf)
+	if err == nil {
+		err = temp.Sync()
+	}
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(temp.Name(), info.Mode().Perm())
+	}
+	if err != nil {
+		return err
+	}
+
+	// the file may have changed while the new one was written, and nothing
+	// else has been touched yet
+	beforeLastCheck()
+	current, err := s.check(path)
+	if err != nil {
+		return err
+	}
+
+	if s.Backups > 0 {
+		for n := s.Backups - 1; n > 0; n-- {
+			err := os.Rename(fmt.Sprintf("%s.%d", path, n),
+				fmt.Sprintf("%s.%d", path, n+1))
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		err := os.WriteFile(path+".1", current, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := os.Rename(temp.Name(), path); err != nil {
+		return err
+	}
+	s.Buffer = append([]byte(nil), buf...)
+	s.hash = sha256.Sum256(buf)
+	return nil
+}
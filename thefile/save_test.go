@@ -0,0 +1,153 @@
+package thefile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file")
+	original := []byte("----one\n\nbody\n\n----two\n\nmore\n")
+	if err := os.WriteFile(path, original, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := LoadSnapshot(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Backups = 2
+	versions := [][]byte{original}
+	for n := 1; n <= 3; n++ {
+		buf := []byte(fmt.Sprintf("----one\n\nversion %d\n", n))
+		if err := s.Save(buf); err != nil {
+			t.Fatal(err)
+		}
+		versions = append(versions, buf)
+	}
+	expected := map[string][]byte{
+		"file":   versions[3],
+		"file.1": versions[2],
+		"file.2": versions[1],
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(expected) {
+		t.Errorf("expected %d files, got %v", len(expected), entries)
+	}
+	for name, contents := range expected {
+		buf, err := os.ReadFile(filepath.Join(filepath.Dir(path), name))
+		if err != nil || string(buf) != string(contents) {
+			t.Errorf("%s:\nexpected: %q\nactual:   %q, %v", name, contents, buf, err)
+		}
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("mode changed: %v, %v", info.Mode(), err)
+	}
+
+	// someone else changes the file
+	changed := []byte("----one\n\nversion 3\n\n----three\n\nnew\n")
+	if err := os.WriteFile(path, changed, 0600); err != nil {
+		t.Fatal(err)
+	}
+	pages, _, err := parsePages(changed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Save([]byte("mine\n"))
+	conflict, ok := err.(ConflictError)
+	if !ok {
+		t.Fatalf("expected ConflictError, got %v", err)
+	}
+	if added := []string{pages[1].Hash64()}; !reflect.DeepEqual(added, conflict.Added) || conflict.Removed != nil {
+		t.Errorf("\nexpected: %v []\nactual:   %v %v", added, conflict.Added, conflict.Removed)
+	}
+	if buf, _ := os.ReadFile(path); string(buf) != string(changed) {
+		t.Errorf("file overwritten despite conflict: %q", buf)
+	}
+}
+
+func TestSaveSymlink(t *testing.T) {
+	dir := t.TempDir()
+	path, link := filepath.Join(dir, "file"), filepath.Join(dir, "link")
+	original := []byte("----one\n\nbody\n")
+	if err := os.WriteFile(path, original, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("file", link); err != nil {
+		t.Skip(err)
+	}
+
+	s, err := LoadSnapshotFrom(FileSource(link), link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Backups = 1
+	buf := []byte("----one\n\nnew\n")
+	if err := s.Save(buf); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("link replaced: %v, %v", info.Mode(), err)
+	}
+	expected := map[string][]byte{"file": buf, "file.1": original}
+	for name, contents := range expected {
+		actual, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil || string(actual) != string(contents) {
+			t.Errorf("%s:\nexpected: %q\nactual:   %q, %v", name, contents, actual, err)
+		}
+	}
+}
+
+func TestSaveLateConflict(t *testing.T) {
+	for _, backups := range []int{0, 2} {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "file")
+		older := map[string][]byte{
+			"file.1": []byte("older1\n"),
+			"file.2": []byte("older2\n"),
+		}
+		for name, contents := range older {
+			if err := os.WriteFile(filepath.Join(dir, name), contents, 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := os.WriteFile(path, []byte("----one\n\nbody\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		s, err := LoadSnapshot(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Backups = backups
+
+		// someone else changes the file while the new one is written
+		changed := []byte("----one\n\nbody\n\n----two\n\nnew\n")
+		beforeLastCheck = func() {
+			if err := os.WriteFile(path, changed, 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		err = s.Save([]byte("mine\n"))
+		beforeLastCheck = func() {}
+		if _, ok := err.(ConflictError); !ok {
+			t.Errorf("%d backups: expected ConflictError, got %v", backups, err)
+		}
+		if buf, _ := os.ReadFile(path); string(buf) != string(changed) {
+			t.Errorf("%d backups: file overwritten despite conflict: %q", backups, buf)
+		}
+		// the backups are untouched too
+		for name, contents := range older {
+			buf, err := os.ReadFile(filepath.Join(dir, name))
+			if err != nil || string(buf) != string(contents) {
+				t.Errorf("%d backups: %s:\nexpected: %q\nactual:   %q, %v",
+					backups, name, contents, buf, err)
+			}
+		}
+	}
+}
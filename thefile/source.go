@@ -0,0 +1,85 @@
+package thefile
+
+import (
+	"io"
+	"io/fs"
+	"os"
+
+	"sethwklein.net/thefile/storage"
+)
+
+// Source is somewhere to read a file in the format of the file from.
+type Source interface {
+	// Load returns the whole file.
+	Load() ([]byte, error)
+}
+
+// SourceFunc is a Source that calls itself to load the file.
+type SourceFunc func() ([]byte, error)
+
+// Load satisfies the Source interface.
+func (f SourceFunc) Load() ([]byte, error) {
+	return f()
+}
+
+// Storage is the file in the configured storage location, which Pages and
+// the other functions without a source read.
+var Storage Source = SourceFunc(storage.Load)
+
+// FileSource returns a Source that reads the file at path.
+func FileSource(path string) Source {
+	return SourceFunc(func() ([]byte, error) {
+		return os.ReadFile(path)
+	})
+}
+
+// FSSource returns a Source that reads the named file in fsys.
+func FSSource(fsys fs.FS, name string) Source {
+	return SourceFunc(func() ([]byte, error) {
+		return fs.ReadFile(fsys, name)
+	})
+}
+
+// ReaderSource returns a Source that reads r to the end. Loading it again
+// reads whatever is left of r, which is usually nothing.
+func ReaderSource(r io.Reader) Source {
+	return SourceFunc(func() ([]byte, error) {
+		return io.ReadAll(r)
+	})
+}
+
+// BytesSource returns a Source for buf, which must not be modified while the
+// pages are in use.
+func BytesSource(buf []byte) Source {
+	return SourceFunc(func() ([]byte, error) {
+		return buf, nil
+	})
+}
+
+// PagesFrom is like Pages, but reads source, overriding any WithSource.
+func PagesFrom(source Source, opts ...Option) ([]*Page, error) {
+	return Pages(append(opts[:len(opts):len(opts)], WithSource(source))...)
+}
+
+// PagesStatisticsFrom is like PagesStatistics, but reads source, overriding
+// any WithSource.
+func PagesStatisticsFrom(source Source, opts ...Option) ([]*Page, *Statistics, error) {
+	return PagesStatistics(append(opts[:len(opts):len(opts)], WithSource(source))...)
+}
+
+// NestedPagesFrom is like NestedPages, but reads source, overriding any
+// WithSource.
+func NestedPagesFrom(source Source, opts ...Option) ([]*Page, error) {
+	return NestedPages(append(opts[:len(opts):len(opts)], WithSource(source))...)
+}
+
+func loadPages(o *options) (pages []*Page, stats *Statistics, err error) {
+	if o.err != nil {
+		return nil, &Statistics{}, o.err
+	}
+	buf, err := o.source.Load()
+	if err != nil {
+		return nil, &Statistics{}, err
+	}
+	return o.parse(buf)
+}
@@ -0,0 +1,40 @@
+package thefile
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestPagesFrom(t *testing.T) {
+	buf := []byte("----one\n\nbody\n\n----two\n----also two\n\nmore\n")
+	expected, _, err := parsePages(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sources := map[string]Source{
+		"bytes":  BytesSource(buf),
+		"reader": ReaderSource(bytes.NewReader(buf)),
+		"fs": FSSource(fstest.MapFS{
+			"dir/file": &fstest.MapFile{Data: buf},
+		}, "dir/file"),
+	}
+	for name, source := range sources {
+		actual, stats, err := PagesStatisticsFrom(source)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(expected, actual) {
+			t.Errorf("%s:\nexpected: %v\nactual:   %v", name, expected, actual)
+		}
+		if stats.LineCount != 9 {
+			t.Errorf("%s: expected 9 lines, got %d", name, stats.LineCount)
+		}
+	}
+
+	if _, err := PagesFrom(FileSource("no such file")); err == nil {
+		t.Errorf("expected error for missing file")
+	}
+}
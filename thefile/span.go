@@ -0,0 +1,60 @@
+package thefile
+
+import (
+	"sethwklein.net/thefile/parser"
+)
+
+// Span is a run of whole lines of the file, along with what they are.
+type Span struct {
+	Kind parser.SpanKind
+
+	// Page is the page the span is part of. For a parser.ClearSpan, it is
+	// the page the clears are in or before, or nil at the end of the file.
+	Page *Page
+
+	// Line is the number (one based) of the first line.
+	Line int
+
+	// Text is the lines, including their newlines.
+	Text []byte
+}
+
+// Spans returns the pages in buf and spans covering every line of buf exactly
+// once, in order, so that concatenating the Text of the spans reproduces buf
+// as Pages reads it, without a byte order mark and with LF line endings. With
+// WithOriginalLineEndings, it reproduces buf exactly, and the first span
+// includes any byte order mark. It is for tools that rewrite some of the file
+// but must leave the rest of it untouched. Options are as for Pages, except
+// that buf is read instead of a Source.
+func Spans(buf []byte, opts ...Option) ([]Span, []*Page, error) {
+	o := newOptions(opts...)
+	if o.err != nil {
+		return nil, nil, o.err
+	}
+	buf = normalize(buf, o.keepCRLF, &Statistics{})
+	tokens, offsets := o.tokenize(buf)
+	parsed, spans, err := parser.ParseSpans(o.alphabet().Map(tokens))
+	if err != nil {
+		return nil, nil, err
+	}
+	pages := makePages(o, buf, offsets, tokens, parsed)
+
+	result := make([]Span, len(spans))
+	for i, span := range spans {
+		result[i].Kind = span.Kind
+		if span.Page < len(pages) {
+			result[i].Page = pages[span.Page]
+		}
+		result[i].Line = span.Low + 1
+		low := offsets[span.Low]
+		if span.Low == 0 {
+			low = 0
+		}
+		result[i].Text = buf[low:offsets[span.High]]
+	}
+	if len(result) < 1 && len(buf) > 0 {
+		// a byte order mark and no lines
+		result = append(result, Span{Kind: parser.ClearSpan, Line: 1, Text: buf})
+	}
+	return result, pages, nil
+}
@@ -0,0 +1,82 @@
+package thefile
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"sethwklein.net/thefile/parser"
+)
+
+func TestSpans(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n < 500; n++ {
+		buf := randomFile(r, r.Intn(30))
+		spans, pages, err := Spans(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var joined []byte
+		for _, span := range spans {
+			joined = append(joined, span.Text...)
+			if span.Kind == parser.BodySpan &&
+				!bytes.Equal(span.Text, span.Page.Body()) {
+				t.Errorf("\nbuf:  %q\nbody %q in span %q", buf,
+					span.Page.Body(), span.Text)
+			}
+		}
+		if !bytes.Equal(buf, joined) {
+			t.Errorf("\nbuf:    %q\nspans:  %q", buf, joined)
+		}
+		expected, _, _ := parsePages(buf)
+		samePages(t, expected, pages)
+	}
+}
+
+func TestSpansBOM(t *testing.T) {
+	buf := []byte("\ufeff----one\r\n\r\nbody\r\n\r\n----two\r\n\r\nmore\r\n")
+	expected, _, err := parsePages(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, pages, err := Spans(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	samePages(t, expected, pages)
+	if tags := pages[0].Tags(); !reflect.DeepEqual(tags, []string{"one"}) {
+		t.Errorf("\nexpected: [one]\nactual:   %q", tags)
+	}
+	for i := range expected {
+		if !bytes.Equal(expected[i].HashRaw(), pages[i].HashRaw()) {
+			t.Errorf("page %d: hash differs from Pages", i)
+		}
+	}
+
+	// the original bytes are kept
+	spans, pages, err := Spans(buf, WithOriginalLineEndings())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var joined []byte
+	for _, span := range spans {
+		joined = append(joined, span.Text...)
+	}
+	if !bytes.Equal(buf, joined) {
+		t.Errorf("\nbuf:    %q\nspans:  %q", buf, joined)
+	}
+	if tags := pages[0].Tags(); !reflect.DeepEqual(tags, []string{"one"}) {
+		t.Errorf("\nexpected: [one]\nactual:   %q", tags)
+	}
+
+	// even when there is nothing else
+	spans, _, err = Spans([]byte("\ufeff"), WithOriginalLineEndings())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spans) != 1 || string(spans[0].Text) != "\ufeff" ||
+		spans[0].Kind != parser.ClearSpan {
+		t.Errorf("expected a clear span of the byte order mark, got %v", spans)
+	}
+}
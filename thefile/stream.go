@@ -0,0 +1,80 @@
+package thefile
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+
+	"sethwklein.net/thefile/parser"
+	"sethwklein.net/thefile/tokenizer"
+)
+
+// StreamPages reads a file in the format of the file from r, calling fn with
+// each page as soon as it is complete, so that pages are available before
+// the rest of the file has been read. It finds the same pages as Pages. If fn
+// returns an error, StreamPages stops and returns it. Options are as for
+// Pages, except that r is read instead of a Source.
+func StreamPages(r io.Reader, fn func(*Page) error, opts ...Option) error {
+	// pages keep slices of buf and offsets. appending may move them, but
+	// the pages already made keep the old copies, which don't change.
+	var buf []byte
+	var offsets []int
+	var tokens []tokenizer.Token
+
+	o := newOptions(opts...)
+	if o.err != nil {
+		return o.err
+	}
+	alphabet := o.alphabet()
+	index := 0
+	var fnErr error
+	stream := parser.NewStream(func(p parser.Page) {
+		if fnErr != nil {
+			return
+		}
+		page := &Page{}
+		page.set(o, buf, offsets, tokens, p, index)
+		index++
+		fnErr = fn(page)
+	})
+
+	tok, end := o.lines()
+	offset := 0
+	push := func(token tokenizer.Token, length int) error {
+		offsets = append(offsets, offset)
+		tokens = append(tokens, token)
+		offset += length
+		if err := stream.Push(alphabet.Token(token)); err != nil {
+			return err
+		}
+		return fnErr
+	}
+
+	in := bufio.NewReader(r)
+	for first := true; ; first = false {
+		line, err := in.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		// normalize as Pages does, a line at a time
+		if first && bytes.HasPrefix(line, bom) {
+			line = line[len(bom):]
+			if o.keepCRLF {
+				buf = append(buf, bom...)
+				offset = len(bom)
+			}
+		}
+		if !o.keepCRLF && bytes.HasSuffix(line, crlf) {
+			line = append(line[:len(line)-len(crlf)], '\n')
+		}
+		buf = append(buf, line...)
+		if len(line) > 0 {
+			if err := push(tok.Line(buf[offset:])); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return push(end, 0)
+		}
+	}
+}
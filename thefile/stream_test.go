@@ -0,0 +1,72 @@
+package thefile
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"testing/iotest"
+)
+
+var streamTests = []string{
+	"",
+	"\n",
+	"junk\n\n----one\n\nbody\n",
+	"----one\n----two\n\n----three\n\nbody\n\n\n----four\n",
+	"----no newline at end\n\nbody",
+	"\ufeff----one\r\n\r\nbody\r\n",
+	"\ufeff",
+	"----one\r\n\r\nbody\r\n\r\n----two\r\n",
+}
+
+func TestStreamPages(t *testing.T) {
+	for _, opts := range [][]Option{nil, {WithOriginalLineEndings()}} {
+		for _, test := range streamTests {
+			checkStream(t, test, opts)
+		}
+	}
+}
+
+func checkStream(t *testing.T, test string, opts []Option) {
+	expected, _, err := newOptions(opts...).parse([]byte(test))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual []*Page
+	r := iotest.OneByteReader(bytes.NewReader([]byte(test)))
+	err = StreamPages(r, func(page *Page) error {
+		actual = append(actual, page)
+		return nil
+	}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(expected) != len(actual) {
+		t.Errorf("\ninput:    %q\nexpected: %d pages\nactual:   %d pages",
+			test, len(expected), len(actual))
+		return
+	}
+	for i := range expected {
+		e, a := expected[i], actual[i]
+		if !reflect.DeepEqual(e.titles, a.titles) ||
+			!bytes.Equal(e.All(), a.All()) ||
+			!reflect.DeepEqual(e.Lines(), a.Lines()) ||
+			e.Address() != a.Address() || e.Index() != a.Index() ||
+			!bytes.Equal(e.HashRaw(), a.HashRaw()) {
+			t.Errorf("\ninput: %q\npage %d differs", test, i)
+		}
+	}
+}
+
+func TestStreamPagesBOM(t *testing.T) {
+	var tags []string
+	err := StreamPages(bytes.NewReader([]byte("\ufeff----one\r\n\r\nbody\r\n")), func(page *Page) error {
+		tags = page.Tags()
+		if !bytes.Equal(page.Body(), []byte("body\n")) {
+			t.Errorf("body not normalized: %q", page.Body())
+		}
+		return nil
+	})
+	if err != nil || !reflect.DeepEqual(tags, []string{"one"}) {
+		t.Errorf("\nexpected: [one]\nactual:   %q, %v", tags, err)
+	}
+}
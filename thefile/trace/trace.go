@@ -0,0 +1,67 @@
+// Command trace displays the state of the page finding machine for each line
+// of the file, next to the line, to help explain unexpected page splits and
+// merges.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"sethwklein.net/thefile/parser"
+	"sethwklein.net/thefile/thefile"
+)
+
+func mainError() error {
+	from := flag.Int("from", 1, "first line number to display")
+	to := flag.Int("to", 0, "last line number to display, 0 for the end")
+	file := flag.String("file", "", "file to read instead of the one in storage")
+	flag.Parse()
+
+	source := thefile.Storage
+	if *file != "" {
+		source = thefile.FileSource(*file)
+	}
+	buf, err := source.Load()
+	if err != nil {
+		return err
+	}
+
+	tokens, offsets := thefile.Tokenize(buf)
+	_, steps, err := parser.TraceTokens(parser.DefaultAlphabet.Map(tokens))
+
+	out := bufio.NewWriter(os.Stdout)
+	for _, step := range steps {
+		line := step.Index + 1
+		if line < *from || (*to > 0 && line > *to) {
+			continue
+		}
+		text := "(end of file)"
+		if step.Index+1 < len(offsets) {
+			text = string(bytes.TrimRight(
+				buf[offsets[step.Index]:offsets[step.Index+1]], "\r\n"))
+		}
+		fmt.Fprintf(out, "%6d %c %-4s -> %-4s %-40s | %s\n", line,
+			tokens[step.Index], step.From, step.To, step.Actions, text)
+	}
+	if flushErr := out.Flush(); err == nil {
+		err = flushErr
+	}
+	return err
+}
+
+func mainCode() int {
+	err := mainError()
+	if err == nil {
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "%v: Error: %v\n", filepath.Base(os.Args[0]), err)
+	return 1
+}
+
+func main() {
+	os.Exit(mainCode())
+}
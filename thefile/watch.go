@@ -0,0 +1,238 @@
+package thefile
+
+import (
+	"crypto/sha256"
+	"sync"
+	"time"
+)
+
+// Long running tools need the pages as they are now, not as they were when
+// the tool started.
+
+// ChangeKind is the kind of a Change.
+type ChangeKind int
+
+const (
+	// PageAdded is a page that wasn't there before.
+	PageAdded ChangeKind = iota
+	// PageRemoved is a page that isn't there any more.
+	PageRemoved
+	// PageModified is a page with different titles or body, matched to the
+	// old page as by Diff.
+	PageModified
+	// PageMoved is a page that is the same but for its address.
+	PageMoved
+)
+
+func (kind ChangeKind) String() string {
+	switch kind {
+	case PageAdded:
+		return "added"
+	case PageRemoved:
+		return "removed"
+	case PageModified:
+		return "modified"
+	case PageMoved:
+		return "moved"
+	}
+	return "unknown"
+}
+
+// Change describes what happened to a page. Old is nil for PageAdded and New
+// is nil for PageRemoved.
+type Change struct {
+	Kind     ChangeKind
+	Old, New *Page
+}
+
+// Event is sent by a Watcher when the file changes. Index is the index of
+// the new pages, and Changes are sorted by the address of the new page, with
+// removed pages last, by their old address. If the file can't be loaded or
+// parsed, Err is set and Index is the last good index.
+type Event struct {
+	Index   *Index
+	Changes []Change
+	Err     error
+
+	// from is the index Changes are from.
+	from *Index
+}
+
+// merge returns the event that replaces event when next is sent before
+// event is received, with the changes from the older one's index.
+func (event Event) merge(next Event) Event {
+	next.from, next.Changes = event.from, nil
+	if next.Index != event.from {
+		next.Changes = changes(event.from.Pages(), next.Index.Pages())
+	}
+	return next
+}
+
+// changes matches old pages to new pages as Diff does, and returns the
+// changes.
+func changes(old, new []*Page) []Change {
+	var result []Change
+	for _, d := range Diff(old, new) {
+		switch {
+		case d.Difference&Added != 0:
+			result = append(result, Change{PageAdded, nil, d.New})
+		case d.Difference&Deleted != 0:
+			result = append(result, Change{PageRemoved, d.Old, nil})
+		case d.Difference&^(Moved|Reordered) != 0:
+			result = append(result, Change{PageModified, d.Old, d.New})
+		case d.Difference != 0:
+			result = append(result, Change{PageMoved, d.Old, d.New})
+		}
+	}
+	return result
+}
+
+// Watcher keeps an Index of the file up to date, polling it for changes.
+type Watcher struct {
+	o        *options
+	interval time.Duration
+	events   chan Event
+	done     chan struct{}
+	stopped  sync.WaitGroup
+	closing  sync.Once
+
+	// checking serializes Check, so events are sent in order, and guards
+	// hash and err.
+	checking sync.Mutex
+	hash     [sha256.Size]byte
+	// err is the message of the last error sent, so it's sent only once.
+	err string
+
+	mu    sync.RWMutex
+	index *Index
+}
+
+// NewWatcher loads the file and returns a Watcher that checks it for
+// changes every interval, or only when Check is called if interval is 0.
+// Options are as for Pages.
+func NewWatcher(interval time.Duration, opts ...Option) (*Watcher, error) {
+	w := &Watcher{
+		o:        newOptions(opts...),
+		interval: interval,
+		events:   make(chan Event, 1),
+		done:     make(chan struct{}),
+	}
+	buf, err := w.o.source.Load()
+	if err != nil {
+		return nil, err
+	}
+	pages, _, err := w.o.parse(buf)
+	if err != nil {
+		return nil, err
+	}
+	w.index = NewIndex(pages)
+	w.hash = sha256.Sum256(buf)
+
+	if interval > 0 {
+		w.stopped.Add(1)
+		go w.poll()
+	}
+	return w, nil
+}
+
+// Index returns the index of the pages as of the last check.
+func (w *Watcher) Index() *Index {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.index
+}
+
+// Events returns the channel events are sent on. Checks don't wait for
+// events to be received: an event that hasn't been received when the next
+// one is sent is replaced by one with the newer Index and Err, and the
+// Changes since the older one's, so reading it is optional. Close closes
+// the channel, after which an event still in it can be received.
+func (w *Watcher) Events() <-chan Event {
+	return w.events
+}
+
+func (w *Watcher) poll() {
+	defer w.stopped.Done()
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			w.Check()
+		}
+	}
+}
+
+// Check loads the file and, if it changed, swaps in a new Index and sends an
+// Event. It returns whether it sent one. An error is sent only the first
+// time it happens in a row.
+func (w *Watcher) Check() bool {
+	w.checking.Lock()
+	defer w.checking.Unlock()
+	// only Check sets the index, so it can be read without w.mu here
+	old := w.index
+	buf, err := w.o.source.Load()
+	var pages []*Page
+	var hash [sha256.Size]byte
+	if err == nil {
+		hash = sha256.Sum256(buf)
+		if hash == w.hash {
+			w.err = ""
+			return false
+		}
+		pages, _, err = w.o.parse(buf)
+	}
+	if err != nil {
+		repeated := err.Error() == w.err
+		w.err = err.Error()
+		if repeated {
+			return false
+		}
+		return w.send(Event{Index: old, Err: err, from: old})
+	}
+	index := NewIndex(pages)
+	w.mu.Lock()
+	w.index = index
+	w.mu.Unlock()
+	w.hash, w.err = hash, ""
+
+	return w.send(Event{Index: index, Changes: changes(old.Pages(), pages), from: old})
+}
+
+// send sends event unless w is closed, merging it with an event that hasn't
+// been received. Only Check sends, so the buffer is empty the second time
+// around.
+func (w *Watcher) send(event Event) bool {
+	for {
+		select {
+		case <-w.done:
+			return false
+		default:
+		}
+		select {
+		case w.events <- event:
+			return true
+		default:
+		}
+		select {
+		case pending := <-w.events:
+			event = pending.merge(event)
+		default:
+		}
+	}
+}
+
+// Close stops w from polling and sending events, and closes the Events
+// channel. It may be called more than once.
+func (w *Watcher) Close() {
+	w.closing.Do(func() {
+		close(w.done)
+		w.stopped.Wait()
+		// a Check still running may send, but none after it will
+		w.checking.Lock()
+		close(w.events)
+		w.checking.Unlock()
+	})
+}
@@ -0,0 +1,174 @@
+package thefile
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// describe returns changes as strings that are easy to compare.
+func describe(changes []Change) []string {
+	var result []string
+	for _, change := range changes {
+		var name string
+		if change.New != nil {
+			name, _ = change.New.Name()
+		} else {
+			name, _ = change.Old.Name()
+		}
+		result = append(result, change.Kind.String()+" "+name)
+	}
+	return result
+}
+
+func TestWatcher(t *testing.T) {
+	var buf []byte
+	var loadErr error
+	source := SourceFunc(func() ([]byte, error) {
+		return buf, loadErr
+	})
+	buf = []byte("----one\n\nbody\n\n----two\n\nmore\n\n----three\n\nlast\n")
+	w, err := NewWatcher(0, WithSource(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if w.Check() {
+		t.Fatal("event sent for an unchanged file")
+	}
+
+	tests := []struct {
+		buf      string
+		expected []string
+	}{
+		{
+			"----one\n\nbody\n\n----two\n\nedited\n\n----three\n\nlast\n",
+			[]string{"modified two"},
+		},
+		{
+			"----zero\n\nnew\n\n----one\n\nbody\n\n----three\n\nlast\n",
+			[]string{"added zero", "moved one", "removed two"},
+		},
+		{
+			"----zero\n\nnew\n\n----three\n\nlast\n\n----one\n\nbody\n",
+			[]string{"moved three", "moved one"},
+		},
+	}
+	for _, test := range tests {
+		buf = []byte(test.buf)
+		if !w.Check() {
+			t.Fatalf("no event for %q", test.buf)
+		}
+		event := <-w.Events()
+		actual := describe(event.Changes)
+		if event.Err != nil || fmt.Sprint(actual) != fmt.Sprint(test.expected) {
+			t.Errorf("%q:\nexpected: %q\nactual:   %q, %v", test.buf, test.expected, actual, event.Err)
+		}
+		if event.Index != w.Index() || len(w.Index().Pages()) != 3 {
+			t.Errorf("%q: index not swapped", test.buf)
+		}
+	}
+
+	// an error is sent once, and the last good index is kept
+	index := w.Index()
+	loadErr = errors.New("gone")
+	if !w.Check() {
+		t.Fatal("no event for error")
+	}
+	if event := <-w.Events(); event.Err != loadErr || event.Index != index {
+		t.Errorf("expected error event with last index, got %v", event)
+	}
+	if w.Check() {
+		t.Error("repeated error sent again")
+	}
+	loadErr = nil
+	if w.Check() || w.Index() != index {
+		t.Error("event sent when the file came back unchanged")
+	}
+}
+
+func TestWatcherPoll(t *testing.T) {
+	buf := []byte("----one\n\nbody\n")
+	changed := make(chan bool)
+	source := SourceFunc(func() ([]byte, error) {
+		select {
+		case <-changed:
+			buf = []byte("----one\n\nedited\n")
+		default:
+		}
+		return buf, nil
+	})
+	w, err := NewWatcher(time.Millisecond, WithSource(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	changed <- true
+	select {
+	case event := <-w.Events():
+		if actual := describe(event.Changes); fmt.Sprint(actual) != "[modified one]" {
+			t.Errorf("\nexpected: [modified one]\nactual:   %v", actual)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("no event")
+	}
+}
+
+func TestWatcherUnread(t *testing.T) {
+	var buf []byte
+	var loadErr error
+	source := SourceFunc(func() ([]byte, error) {
+		return buf, loadErr
+	})
+	buf = []byte("----one\n\nbody\n\n----two\n\nmore\n")
+	w, err := NewWatcher(0, WithSource(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	// checks don't wait for events to be received
+	for _, test := range []string{
+		"----one\n\nedited\n\n----two\n\nmore\n",
+		"----one\n\nedited\n\n----two\n\nmore\n\n----three\n\nnew\n",
+		"----one\n\nedited\n\n----three\n\nnew\n",
+	} {
+		buf = []byte(test)
+		if !w.Check() {
+			t.Fatalf("no event for %q", test)
+		}
+		if len(w.Index().Pages()) != 2 && len(w.Index().Pages()) != 3 {
+			t.Fatalf("%q: index not swapped", test)
+		}
+	}
+	loadErr = errors.New("gone")
+	if !w.Check() {
+		t.Fatal("no event for error")
+	}
+
+	// the events were merged into one with the changes since the first
+	event := <-w.Events()
+	expected := []string{"modified one", "added three", "removed two"}
+	if actual := describe(event.Changes); fmt.Sprint(actual) != fmt.Sprint(expected) {
+		t.Errorf("\nexpected: %q\nactual:   %q", expected, actual)
+	}
+	if event.Err != loadErr || event.Index != w.Index() {
+		t.Errorf("expected error event with last index, got %v", event)
+	}
+	select {
+	case event := <-w.Events():
+		t.Errorf("unexpected event: %v", event)
+	default:
+	}
+
+	w.Close()
+	w.Close()
+	buf = []byte("----one\n")
+	if w.Check() {
+		t.Error("event sent after Close")
+	}
+	for event := range w.Events() {
+		t.Errorf("unexpected event after Close: %v", event)
+	}
+}